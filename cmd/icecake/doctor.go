@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// icecakeJSFunctions are the functions of icecake.js called by the ick package
+var icecakeJSFunctions = []string{"ickError", "ickWarn", "ickLocalStorage", "ickSessionStorage", "ickStorageSetItem", "ickDefineElement"}
+
+// doctor_skipDirs are not scanned by the doctor
+var doctor_skipDirs = map[string]bool{".git": true, "node_modules": true, "vendor": true}
+
+// problem is an issue found by the doctor, with its optional automatic fix
+type problem struct {
+	msg    string
+	fixmsg string
+	fix    func() error // nil if there's no automatic fix
+}
+
+// doctor runs the "icecake doctor" command, checking the go toolchain, the js files and the settings
+func doctor(_args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ExitOnError)
+	s := addSettingsFlags(flags)
+	root := flags.String("dir", ".", "the root directory of the projects to check")
+	fixall := flags.Bool("fix", false, "fixes every problem without asking")
+	flags.Parse(_args)
+
+	problems := make([]problem, 0)
+	report := func(_ok bool, _format string, _args ...any) {
+		mark := "✓"
+		if !_ok {
+			mark = "✗"
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(_format, _args...))
+	}
+
+	// the go toolchain and its wasm_exec.js
+	goversion, err := exec.Command("go", "env", "GOVERSION").Output()
+	if err != nil {
+		return fmt.Errorf("go toolchain not found: %w", err)
+	}
+	report(true, "go toolchain %s", strings.TrimSpace(string(goversion)))
+	refwasmexec := []byte(nil)
+	wasmexec, err := goWasmExecJS()
+	if err == nil {
+		refwasmexec, err = os.ReadFile(wasmexec)
+	}
+	if err != nil {
+		report(false, "%s", err)
+		problems = append(problems, problem{msg: err.Error()})
+	}
+
+	// the js files of every projects
+	refhelpers, _ := scaffold.ReadFile("scaffold/web/static/icecake.js")
+	err = filepath.WalkDir(*root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if doctor_skipDirs[d.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch d.Name() {
+		case "wasm_exec.js":
+			if refwasmexec == nil {
+				return nil
+			}
+			if p := checkWasmExec(name, refwasmexec, wasmexec); p != nil {
+				report(false, "%s", p.msg)
+				problems = append(problems, *p)
+			} else {
+				report(true, "%s matches the go toolchain", name)
+			}
+		case "icecake.js":
+			if p := checkIcecakeJS(name, refhelpers); p != nil {
+				report(false, "%s", p.msg)
+				problems = append(problems, *p)
+			} else {
+				report(true, "%s defines every helper functions", name)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// the settings and the static files directory
+	if err := s.load(); err != nil {
+		report(false, "%s", err)
+		problems = append(problems, problem{msg: err.Error()})
+	} else {
+		report(true, "settings of %q are valid", s.env)
+	}
+	static := staticDir()
+	for _, p := range checkStaticDir(static, wasmexec) {
+		report(false, "%s", p.msg)
+		problems = append(problems, p)
+	}
+
+	return fixProblems(problems, *fixall)
+}
+
+// checkWasmExec compares the _name wasm_exec.js file with the one of the go toolchain
+func checkWasmExec(_name string, _ref []byte, _refname string) *problem {
+	content, err := os.ReadFile(_name)
+	if err != nil {
+		return &problem{msg: err.Error()}
+	}
+	if bytes.Equal(content, _ref) {
+		return nil
+	}
+	return &problem{
+		msg:    fmt.Sprintf("%s does not match the go toolchain", _name),
+		fixmsg: fmt.Sprintf("copy %s to %s", _refname, _name),
+		fix:    func() error { return os.WriteFile(_name, _ref, 0644) },
+	}
+}
+
+// checkIcecakeJS checks that the _name icecake.js file defines every helper functions called by the ick package
+func checkIcecakeJS(_name string, _ref []byte) *problem {
+	content, err := os.ReadFile(_name)
+	if err != nil {
+		return &problem{msg: err.Error()}
+	}
+	missing := make([]string, 0)
+	for _, fn := range icecakeJSFunctions {
+		if !regexp.MustCompile(`\bfunction\s+` + fn + `\s*\(`).Match(content) {
+			missing = append(missing, fn)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &problem{
+		msg:    fmt.Sprintf("%s misses the %s functions", _name, strings.Join(missing, ", ")),
+		fixmsg: fmt.Sprintf("replace %s with the icecake.js of this icecake version", _name),
+		fix:    func() error { return os.WriteFile(_name, _ref, 0644) },
+	}
+}
+
+// checkStaticDir checks that the _dir static files directory holds the files required to run the wasm app
+func checkStaticDir(_dir string, _wasmexec string) []problem {
+	if embeddedStatic() != nil {
+		return nil
+	}
+	if info, err := os.Stat(_dir); err != nil || !info.IsDir() {
+		return []problem{{
+			msg:    fmt.Sprintf("static files directory %q not found", _dir),
+			fixmsg: fmt.Sprintf("create %q", _dir),
+			fix:    func() error { return os.MkdirAll(_dir, 0755) },
+		}}
+	}
+	problems := make([]problem, 0)
+	if _, err := os.Stat(filepath.Join(_dir, "index.html")); err != nil {
+		problems = append(problems, problem{msg: fmt.Sprintf("%q has no index.html page", _dir)})
+	}
+	if _, err := os.Stat(filepath.Join(_dir, "spa.wasm")); err != nil {
+		problems = append(problems, problem{msg: fmt.Sprintf("%q has no spa.wasm file, run icecake build", _dir)})
+	}
+	target := filepath.Join(_dir, "wasm_exec.js")
+	if _, err := os.Stat(target); err != nil && _wasmexec != "" {
+		problems = append(problems, problem{
+			msg:    fmt.Sprintf("%q has no wasm_exec.js file", _dir),
+			fixmsg: fmt.Sprintf("copy %s to %s", _wasmexec, target),
+			fix: func() error {
+				content, err := os.ReadFile(_wasmexec)
+				if err != nil {
+					return err
+				}
+				return os.WriteFile(target, content, 0644)
+			},
+		})
+	}
+	return problems
+}
+
+// fixProblems applies the fixes of the _problems, without asking if _fixall, otherwise asking on an interactive terminal.
+// Returns an error if problems remain.
+func fixProblems(_problems []problem, _fixall bool) error {
+	// a char device like /dev/null is not a terminal
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	stdin := bufio.NewReader(os.Stdin)
+
+	remaining := 0
+	for _, p := range _problems {
+		if p.fix == nil {
+			remaining++
+			continue
+		}
+		if !_fixall {
+			if !interactive {
+				fmt.Printf("  fixable with -fix: %s\n", p.fixmsg)
+				remaining++
+				continue
+			}
+			fmt.Printf("%s? [y/N] ", p.fixmsg)
+			answer, _ := stdin.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				remaining++
+				continue
+			}
+		}
+		if err := p.fix(); err != nil {
+			fmt.Printf("✗ %s failed: %s\n", p.fixmsg, err)
+			remaining++
+			continue
+		}
+		fmt.Printf("✓ fixed: %s\n", p.fixmsg)
+	}
+
+	if remaining > 0 {
+		return fmt.Errorf("%d problem(s) remaining", remaining)
+	}
+	fmt.Println("everything is fine")
+	return nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckJSFiles(t *testing.T) {
+	dir := t.TempDir()
+	ref := []byte("// reference wasm_exec.js")
+
+	wasmexec := filepath.Join(dir, "wasm_exec.js")
+	os.WriteFile(wasmexec, []byte("// outdated wasm_exec.js"), 0644)
+	p := checkWasmExec(wasmexec, ref, "goroot/wasm_exec.js")
+	if p == nil || p.fix == nil {
+		t.Fatal("fixable problem expected on outdated wasm_exec.js")
+	}
+	if err := p.fix(); err != nil {
+		t.Fatal(err)
+	}
+	if p := checkWasmExec(wasmexec, ref, "goroot/wasm_exec.js"); p != nil {
+		t.Errorf("fixed wasm_exec.js expected, got %q", p.msg)
+	}
+
+	refhelpers, _ := scaffold.ReadFile("scaffold/web/static/icecake.js")
+	if p := checkIcecakeJS("", refhelpers); p == nil || p.fix != nil {
+		t.Errorf("unfixable problem expected on a missing file")
+	}
+	icecakejs := filepath.Join(dir, "icecake.js")
+	os.WriteFile(icecakejs, []byte("function ickError(msg) {}\nfunction ickWarn (msg) {}"), 0644)
+	p = checkIcecakeJS(icecakejs, refhelpers)
+	if p == nil || !strings.Contains(p.msg, "ickLocalStorage, ickSessionStorage, ickStorageSetItem, ickDefineElement functions") {
+		t.Fatalf("missing functions expected, got %+v", p)
+	}
+	p.fix()
+	if p := checkIcecakeJS(icecakejs, refhelpers); p != nil {
+		t.Errorf("the reference icecake.js must define every helper functions: %s", p.msg)
+	}
+}
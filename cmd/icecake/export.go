@@ -0,0 +1,33 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/sunraylab/icecake/pkg/spaserver"
+)
+
+// export runs the "icecake export" command, writing the static site of the routes file into the output directory
+func export(_args []string) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	s := addSettingsFlags(flags)
+	routesfile := flags.String("routes", "./export.json", "json file listing the routes to export")
+	outdir := flags.String("out", "./dist", "output directory of the static site")
+	flags.Parse(_args)
+	if err := s.load(); err != nil {
+		return err
+	}
+
+	routes, err := spaserver.LoadExportRoutes(*routesfile)
+	if err != nil {
+		return err
+	}
+
+	spa := spaserver.MakeWebserver()
+	spa.StaticFS = embeddedStatic()
+	if err := spa.Export(routes, *outdir); err != nil {
+		return err
+	}
+	fmt.Printf("%d routes exported into %q\n", len(routes), *outdir)
+	return nil
+}
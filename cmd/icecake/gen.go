@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"github.com/sunraylab/icecake/internal/ickc"
+)
+
+// generators holds the templates of the generated files, with [[ ]] delimiters.
+//
+//go:embed gen
+var generators embed.FS
+
+var rexpComponentName = regexp.MustCompile(`^[a-z][a-z0-9]*(-[a-z0-9]+)*$`)
+
+// gen runs the "icecake gen" command
+func gen(_args []string) error {
+	if len(_args) > 0 {
+		switch _args[0] {
+		case "component":
+			return genComponent(_args[1:])
+		case "templates":
+			return genTemplates(_args[1:])
+		}
+	}
+	fmt.Fprintln(os.Stderr, "Usage:\n\ticecake gen component [flags] <name>\n\ticecake gen templates [flags]")
+	return fmt.Errorf("unknown generator, component or templates expected")
+}
+
+// genTemplates runs the "icecake gen templates" command, compiling the .ick.html templates of a component package
+// into go render functions. It's designed to be called by go generate:
+//
+//	//go:generate go run github.com/sunraylab/icecake/cmd/icecake gen templates
+func genTemplates(_args []string) error {
+	flags := flag.NewFlagSet("gen templates", flag.ExitOnError)
+	dir := flags.String("dir", ".", "the directory of the component package")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: icecake gen templates [flags]\n\nCompiles every <name>.ick.html template into the RenderHTML method of its component, in the <name>_ick.go file.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(_args)
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments %v", flags.Args())
+	}
+
+	files, err := ickc.Generate(*dir)
+	for _, file := range files {
+		fmt.Printf("generated %s\n", file)
+	}
+	if err != nil {
+		return fmt.Errorf("compilation failed\n%w", err)
+	}
+	if len(files) == 0 {
+		fmt.Printf("no %s template found in %s\n", ickc.TEMPLATE_EXT, *dir)
+	}
+	return nil
+}
+
+// genComponent runs the "icecake gen component" command, creating the go, css and test files of a new component
+func genComponent(_args []string) error {
+	flags := flag.NewFlagSet("gen component", flag.ExitOnError)
+	dir := flags.String("dir", "./web/components", "the directory of the component package")
+	pkg := flags.String("pkg", "", "the package name, the directory name by default")
+	force := flags.Bool("force", false, "overwrites existing files")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: icecake gen component [flags] <name>\n\n<name> is the kebab-case name of the component, used as the <ick-name/> tag.")
+		flags.PrintDefaults()
+	}
+	// flags are accepted before and after the name
+	flags.Parse(_args)
+	name := flags.Arg(0)
+	if name != "" {
+		flags.Parse(flags.Args()[1:])
+	}
+	if name == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return fmt.Errorf("a single component name is required")
+	}
+
+	name = strings.TrimPrefix(strings.ToLower(name), "ick-")
+	if !rexpComponentName.MatchString(name) {
+		return fmt.Errorf("invalid component name %q, kebab-case expected, ie. user-card", name)
+	}
+
+	data := struct {
+		Name    string // the kebab-case name, ie. user-card
+		Type    string // the go type, ie. UserCard
+		Var     string // the go variable prefix, ie. userCard
+		File    string // the file name without extension, ie. user_card
+		Package string // the go package name
+		Dir     string // the package directory
+	}{
+		Name:    name,
+		File:    strings.ReplaceAll(name, "-", "_"),
+		Package: *pkg,
+		Dir:     filepath.ToSlash(filepath.Clean(*dir)),
+	}
+	for _, word := range strings.Split(name, "-") {
+		data.Type += strings.ToUpper(word[:1]) + word[1:]
+	}
+	data.Var = strings.ToLower(data.Type[:1]) + data.Type[1:]
+	if data.Package == "" {
+		data.Package = strings.ToLower(regexp.MustCompile(`[^A-Za-z0-9]`).ReplaceAllString(filepath.Base(data.Dir), ""))
+	}
+	if data.Package == "" || data.Package[0] >= '0' && data.Package[0] <= '9' {
+		return fmt.Errorf("invalid package name %q, use the -pkg flag", data.Package)
+	}
+
+	files := [][2]string{
+		{"gen/component.go.tmpl", data.File + ".go"},
+		{"gen/component.css.tmpl", data.File + ".css"},
+		{"gen/component_test.go.tmpl", data.File + "_test.go"},
+	}
+	if !*force {
+		for _, f := range files {
+			target := f[1]
+			if _, err := os.Stat(filepath.Join(*dir, target)); err == nil {
+				return fmt.Errorf("%q already exists, use -force to overwrite it", filepath.Join(*dir, target))
+			}
+		}
+	}
+	for _, f := range files {
+		tmplname, target := f[0], f[1]
+		content, err := generators.ReadFile(tmplname)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(tmplname).Delims("[[", "]]").Parse(string(content))
+		if err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return err
+		}
+		if err := writeProjectFile(*dir, target, out.Bytes()); err != nil {
+			return err
+		}
+		fmt.Printf("created %s\n", filepath.Join(*dir, target))
+	}
+
+	fmt.Printf("\nimport the %q package into your wasm code to register the component, then use it:\n\n", data.Package)
+	fmt.Printf("\telem.RenderComponent(&%s.%s{Title: \"Hello\"}, nil)\n\n", data.Package, data.Type)
+	fmt.Printf("or embed it into an html template:\n\n")
+	fmt.Printf("\telem.RenderTemplate(`<ick-%s Title=\"Hello\"/>`, nil)\n", data.Name)
+	return nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenComponent(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "my-cards")
+	if err := genComponent([]string{"ick-User-Card", "-dir", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"user_card.go", "user_card_test.go"} {
+		src, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		formatted, err := format.Source(src)
+		if err != nil {
+			t.Fatalf("%s: invalid go source: %s", name, err)
+		}
+		if string(formatted) != string(src) {
+			t.Errorf("%s: source not gofmt-ed", name)
+		}
+		if !strings.HasPrefix(string(src), "package mycards") && !strings.Contains(string(src), "\npackage mycards\n") {
+			t.Errorf("%s: package mycards expected", name)
+		}
+	}
+	src, _ := os.ReadFile(filepath.Join(dir, "user_card.go"))
+	for _, expected := range []string{`//go:embed "user_card.css"`, `ick.MustRegister[UserCard]("ick-user-card", ick.WithCSS(userCardCSS))`, "type UserCard struct"} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("%q expected in the generated component", expected)
+		}
+	}
+	if css, _ := os.ReadFile(filepath.Join(dir, "user_card.css")); !strings.Contains(string(css), ".ick-user-card .ick-user-card-title") {
+		t.Errorf("scoped selectors expected in the generated css")
+	}
+
+	if err := genComponent([]string{"user-card", "-dir", dir}); err == nil {
+		t.Errorf("existing files must not be overwritten without -force")
+	}
+	if err := genComponent([]string{"-dir", dir, "User_Card"}); err == nil {
+		t.Errorf("invalid name must fail")
+	}
+}
@@ -1,35 +1,95 @@
 // icecake server CLI
 //
-// Run the werserver
+// Usage:
+//
+//	icecake <command> [flags]
+//
+// The commands are:
+//
+//	serve    run the spa web server, the default command
+//	dev      build the wasm code and run the spa web server with development settings
+//	build    build the wasm code into the static files directory
+//	export   export a static site
+//	new      create a new icecake project
+//	gen      generate a new component, or compile the component templates
+//	doctor   check the go toolchain, the js files and the settings
+//	size     report the code size of a wasm file per package and function
+//	version  print the icecake version
+//
+// Use "icecake <command> -h" for more information about a command.
 package main
 
 import (
-	"flag"
-	"log"
-
-	"github.com/joho/godotenv"
-	"github.com/sunraylab/icecake/pkg/spaserver"
+	"fmt"
+	"os"
+	"strings"
 )
 
+type command struct {
+	name  string
+	short string
+	run   func(_args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"serve", "run the spa web server, the default command", serve},
+		{"dev", "build the wasm code and run the spa web server with development settings", dev},
+		{"build", "build the wasm code into the static files directory", build},
+		{"export", "export a static site", export},
+		{"new", "create a new icecake project", newProject},
+		{"gen", "generate a new component, or compile the component templates", gen},
+		{"doctor", "check the go toolchain, the js files and the settings", doctor},
+		{"size", "report the code size of a wasm file per package and function", size},
+		{"version", "print the icecake version", printVersion},
+	}
+}
+
 func main() {
-	// get --env flag
-	strenv := "dev"
-	env := flag.String("env", "dev", ".env environement file to load, with the path and without the extension. dev by default.")
-	flag.Parse()
-	if env != nil {
-		strenv = *env
+	// the help flags print the commands, not the flags of the default command
+	if len(os.Args) > 1 && isHelpFlag(os.Args[1]) {
+		usage()
+		return
+	}
+
+	// the serve command is the default one, so "icecake -env prod" still runs the server
+	name, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	// load environment variables
-	err := godotenv.Load(strenv + ".env")
-	if err != nil {
-		log.Fatalf("Error loading .env variables: %s", err)
+	for _, cmd := range commands {
+		if cmd.name == name {
+			if err := cmd.run(args); err != nil {
+				fmt.Fprintf(os.Stderr, "icecake %s: %s\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
 	}
 
-	// Make a web server a add APIs route handlers
-	spa := spaserver.MakeWebserver()
-	//spa.ApiRouter.HandleFunc("/login", api.ServeLogin())
+	if name != "help" {
+		fmt.Fprintf(os.Stderr, "icecake: unknown command %q\n\n", name)
+	}
+	usage()
+	os.Exit(2)
+}
 
-	// Let's start the server, listen requests and serve answers
-	spa.Run()
+// isHelpFlag returns true if _arg is one of the help flags of the flag package
+func isHelpFlag(_arg string) bool {
+	switch _arg {
+	case "-h", "-help", "--h", "--help":
+		return true
+	}
+	return false
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, "Usage:\n\n\ticecake <command> [flags]\n\nThe commands are:\n\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "\t%-8s %s\n", cmd.name, cmd.short)
+	}
+	fmt.Fprintln(os.Stderr, "\nUse \"icecake <command> -h\" for more information about a command.")
 }
@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestIsHelpFlag(t *testing.T) {
+	tests := []struct {
+		arg    string
+		wanted bool
+	}{
+		{arg: "-h", wanted: true},
+		{arg: "--help", wanted: true},
+		{arg: "-env", wanted: false},
+		{arg: "help", wanted: false},
+	}
+	for _, tst := range tests {
+		if got := isHelpFlag(tst.arg); got != tst.wanted {
+			t.Errorf("%q: %v expected, got %v", tst.arg, tst.wanted, got)
+		}
+	}
+}
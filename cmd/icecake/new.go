@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+)
+
+// scaffold holds the files of a new project. Files with the .tmpl extension are templates with [[ ]] delimiters.
+//
+//go:embed all:scaffold
+var scaffold embed.FS
+
+var rexpRelease = regexp.MustCompile(`^v\d+\.\d+\.\d+(-(alpha|beta|rc)[.\d]*)?$`)
+
+// newProject runs the "icecake new" command, creating a new project in the directory given as argument
+func newProject(_args []string) error {
+	flags := flag.NewFlagSet("new", flag.ExitOnError)
+	module := flags.String("module", "", "the go module path of the new project, the directory name by default")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: icecake new [flags] <directory>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(_args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return fmt.Errorf("the project directory is required")
+	}
+
+	dir := flags.Arg(0)
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return fmt.Errorf("directory %q already exists and is not empty", dir)
+	}
+
+	data := struct {
+		Name           string
+		Module         string
+		IcecakeVersion string
+	}{
+		Name:   filepath.Base(dir),
+		Module: *module,
+	}
+	if data.Module == "" {
+		data.Module = data.Name
+	}
+	// only released versions can be required, not the pseudo-versions of local builds
+	if info, ok := debug.ReadBuildInfo(); ok && rexpRelease.MatchString(info.Main.Version) {
+		data.IcecakeVersion = info.Main.Version
+	}
+
+	err := fs.WalkDir(scaffold, "scaffold", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := scaffold.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		target := strings.TrimPrefix(name, "scaffold/")
+		if path.Ext(name) == ".tmpl" {
+			target = strings.TrimSuffix(target, ".tmpl")
+			tmpl, err := template.New(name).Delims("[[", "]]").Parse(string(content))
+			if err != nil {
+				return err
+			}
+			var out bytes.Buffer
+			if err := tmpl.Execute(&out, data); err != nil {
+				return err
+			}
+			content = out.Bytes()
+		}
+		return writeProjectFile(dir, target, content)
+	})
+	if err != nil {
+		return fmt.Errorf("creating the project failed: %w", err)
+	}
+
+	// the wasm_exec.js file must match the go toolchain
+	wasmexec, err := goWasmExecJS()
+	if err == nil {
+		var content []byte
+		if content, err = os.ReadFile(wasmexec); err == nil {
+			err = writeProjectFile(dir, "web/static/wasm_exec.js", content)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: wasm_exec.js not copied: %s\n", err)
+	}
+
+	fmt.Printf("project %q created in %q, next steps:\n\n", data.Module, dir)
+	fmt.Printf("\tcd %s\n", dir)
+	if data.IcecakeVersion == "" {
+		fmt.Println("\tgo get github.com/sunraylab/icecake@latest")
+	}
+	fmt.Println("\tgo mod tidy")
+	fmt.Println("\ticecake dev -env ./configs/dev")
+	return nil
+}
+
+// writeProjectFile writes _content into the _name file of the _dir project
+func writeProjectFile(_dir string, _name string, _content []byte) error {
+	filename := filepath.Join(_dir, filepath.FromSlash(_name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, _content, 0644)
+}
+
+// goWasmExecJS returns the path of the wasm_exec.js file of the go toolchain
+func goWasmExecJS() (string, error) {
+	out, err := exec.Command("go", "env", "GOROOT").Output()
+	if err != nil {
+		return "", fmt.Errorf("go env GOROOT failed: %w", err)
+	}
+	goroot := strings.TrimSpace(string(out))
+	// moved from misc/wasm to lib/wasm with go 1.24
+	for _, dir := range []string{"lib/wasm", "misc/wasm"} {
+		filename := filepath.Join(goroot, filepath.FromSlash(dir), "wasm_exec.js")
+		if _, err := os.Stat(filename); err == nil {
+			return filename, nil
+		}
+	}
+	return "", fmt.Errorf("wasm_exec.js not found in GOROOT %q", goroot)
+}
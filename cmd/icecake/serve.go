@@ -0,0 +1,102 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/sunraylab/icecake/pkg/spaserver"
+)
+
+// serve runs the "icecake serve" command
+func serve(_args []string) error {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	s := addSettingsFlags(flags)
+	flags.Parse(_args)
+	if err := s.load(); err != nil {
+		return err
+	}
+	runServer()
+	return nil
+}
+
+// dev runs the "icecake dev" command, building the wasm code before running the server with the logger on and without cache.
+// The wasm code is not built when the static files are embedded into the binary, the embedded spa.wasm being served.
+func dev(_args []string) error {
+	flags := flag.NewFlagSet("dev", flag.ExitOnError)
+	s := addSettingsFlags(flags)
+	src := flags.String("src", "./web/wasm", "the wasm main package to build")
+	nobuild := flags.Bool("nobuild", false, "runs the server without building the wasm code")
+	flags.Parse(_args)
+	if err := s.load(); err != nil {
+		return err
+	}
+	if !s.isSet("log") {
+		os.Setenv("HTTP_LOGGER", "true")
+	}
+	if !s.isSet("cache") {
+		os.Setenv("HTTP_CACHE_CONTROL", "false")
+	}
+	if embeddedStatic() != nil {
+		fmt.Println("the static files are embedded into this binary, the wasm code is not built")
+	} else if !*nobuild {
+		if err := buildWasm(*src, filepath.Join(staticDir(), "spa.wasm")); err != nil {
+			return err
+		}
+	}
+	runServer()
+	return nil
+}
+
+// build runs the "icecake build" command
+func build(_args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	s := addSettingsFlags(flags)
+	src := flags.String("src", "./web/wasm", "the wasm main package to build")
+	out := flags.String("o", "", "the output wasm file, spa.wasm within the static files directory by default")
+	flags.Parse(_args)
+	if err := s.load(); err != nil {
+		return err
+	}
+	if *out == "" {
+		if embeddedStatic() != nil {
+			return fmt.Errorf("-o is required, the static files are embedded into this binary")
+		}
+		*out = filepath.Join(staticDir(), "spa.wasm")
+	}
+	return buildWasm(*src, *out)
+}
+
+// runServer makes the web server and runs it until it's shut down
+func runServer() {
+	// Make a web server a add APIs route handlers
+	spa := spaserver.MakeWebserver()
+	spa.StaticFS = embeddedStatic()
+	//spa.ApiRouter.HandleFunc("/login", api.ServeLogin())
+
+	// Let's start the server, listen requests and serve answers
+	spa.Run()
+}
+
+// staticDir returns the directory of the static files
+func staticDir() string {
+	if dir := os.Getenv("SPA_STATICFILEDIR"); dir != "" {
+		return dir
+	}
+	return "./web/static"
+}
+
+// buildWasm compiles the _src package into the _out wasm file
+func buildWasm(_src string, _out string) error {
+	fmt.Printf("building %q into %q\n", _src, _out)
+	cmd := exec.Command("go", "build", "-o", _out, _src)
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("building the wasm code failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/sunraylab/icecake/pkg/spaserver"
+)
+
+// settings are the flags shared by the commands loading the spa server settings.
+// Flags override the env variables.
+type settings struct {
+	flags  *flag.FlagSet
+	env    string
+	port   string
+	static string
+	cache  bool
+	logger bool
+	set    []string
+}
+
+// addSettingsFlags defines the settings flags within _flags
+func addSettingsFlags(_flags *flag.FlagSet) *settings {
+	s := &settings{flags: _flags}
+	_flags.StringVar(&s.env, "env", "dev", "env file to load, with the path and without the .env extension. The .env and .env.local files of the same directory are loaded first.")
+	_flags.StringVar(&s.port, "port", "", "the spa server port, overrides HTTP_PORT")
+	_flags.StringVar(&s.static, "static", "", "the directory of the static files, overrides SPA_STATICFILEDIR")
+	_flags.BoolVar(&s.cache, "cache", false, "enables the http cache control, overrides HTTP_CACHE_CONTROL")
+	_flags.BoolVar(&s.logger, "log", false, "logs every http request, overrides HTTP_LOGGER")
+	_flags.Func("set", "overrides any setting with a NAME=value pair, can be repeated", func(_value string) error {
+		if name, _, found := strings.Cut(_value, "="); !found || strings.Trim(name, " ") == "" {
+			return fmt.Errorf("NAME=value expected")
+		}
+		s.set = append(s.set, _value)
+		return nil
+	})
+	return s
+}
+
+// isSet returns true if the _name flag has been set on the command line
+func (_s *settings) isSet(_name string) bool {
+	set := false
+	_s.flags.Visit(func(f *flag.Flag) {
+		if f.Name == _name {
+			set = true
+		}
+	})
+	return set
+}
+
+// load loads the env files, applies the flags overrides, and validates the resulting settings.
+func (_s *settings) load() error {
+	defined, err := loadEnvFiles(_s.env, _s.isSet("env"))
+	if err != nil {
+		return err
+	}
+
+	if _s.port != "" {
+		if !strings.Contains(_s.port, ":") {
+			_s.port = ":" + _s.port
+		}
+		os.Setenv("HTTP_PORT", _s.port)
+	}
+	if _s.static != "" {
+		// the embedded files are served whatever the static files directory
+		if embeddedStatic() != nil {
+			return fmt.Errorf("-static can't be used, the static files are embedded into this binary")
+		}
+		os.Setenv("SPA_STATICFILEDIR", _s.static)
+	}
+	if _s.isSet("cache") {
+		os.Setenv("HTTP_CACHE_CONTROL", fmt.Sprint(_s.cache))
+	}
+	if _s.isSet("log") {
+		os.Setenv("HTTP_LOGGER", fmt.Sprint(_s.logger))
+	}
+	for _, pair := range _s.set {
+		name, value, _ := strings.Cut(pair, "=")
+		name = strings.Trim(name, " ")
+		os.Setenv(name, value)
+		defined = append(defined, name)
+	}
+
+	if err := spaserver.ValidateSettings(defined); err != nil {
+		return fmt.Errorf("invalid settings:\n%w", err)
+	}
+	return nil
+}
+
+// loadEnvFiles loads the .env, .env.local and <_env>.env files, in this order, from the directory of _env.
+// Values of a file override the ones of the previous files, but variables already set in the environment take precedence.
+// Missing files are ignored, unless the <_env>.env file is _required.
+//
+// Returns the names of the variables defined within the files.
+func loadEnvFiles(_env string, _required bool) (_defined []string, _err error) {
+	dir := filepath.Dir(_env)
+	files := []string{filepath.Join(dir, ".env"), filepath.Join(dir, ".env.local"), _env + ".env"}
+
+	values := make(map[string]string)
+	for i, file := range files {
+		filevalues, err := godotenv.Read(file)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) && !(_required && i == len(files)-1) {
+				continue
+			}
+			return nil, fmt.Errorf("loading env file %q: %w", file, err)
+		}
+		for name, value := range filevalues {
+			values[name] = value
+		}
+	}
+
+	for name, value := range values {
+		if _, found := os.LookupEnv(name); !found {
+			os.Setenv(name, value)
+		}
+		_defined = append(_defined, name)
+	}
+	return _defined, nil
+}
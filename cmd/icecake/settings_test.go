@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadEnvFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, ".env"), []byte("ICK_TEST_A=env\nICK_TEST_B=env\nICK_TEST_C=env\nICK_TEST_D=env\n"), 0644)
+	os.WriteFile(filepath.Join(dir, ".env.local"), []byte("ICK_TEST_B=local\nICK_TEST_C=local\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "prod.env"), []byte("ICK_TEST_C=prod\nICK_TEST_D=prod\n"), 0644)
+	t.Setenv("ICK_TEST_D", "os")
+
+	defined, err := loadEnvFiles(filepath.Join(dir, "prod"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(defined) != 4 {
+		t.Errorf("4 defined variables expected, got %v", defined)
+	}
+	expected := map[string]string{"ICK_TEST_A": "env", "ICK_TEST_B": "local", "ICK_TEST_C": "prod", "ICK_TEST_D": "os"}
+	for name, value := range expected {
+		if got := os.Getenv(name); got != value {
+			t.Errorf("%s: %q expected, got %q", name, value, got)
+		}
+		os.Unsetenv(name)
+	}
+
+	if _, err := loadEnvFiles(filepath.Join(dir, "missing"), false); err != nil {
+		t.Errorf("missing optional env file must be ignored, got %s", err)
+	}
+	if _, err := loadEnvFiles(filepath.Join(dir, "missing"), true); err == nil {
+		t.Errorf("missing required env file must fail")
+	}
+}
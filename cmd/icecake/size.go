@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sunraylab/icecake/internal/wasmsize"
+)
+
+// size runs the "icecake size" command, reporting the code size of a wasm file per go package and per function
+func size(_args []string) error {
+	flags := flag.NewFlagSet("size", flag.ExitOnError)
+	top := flags.Int("top", 20, "the number of biggest functions to report")
+	asjson := flags.Bool("json", false, "output the report in json, can be saved to be diffed with a later build")
+	diff := flags.String("diff", "", "a previous build, wasm file or json report, to compare with")
+	maxgrowth := flags.String("max-growth", "", "fails if the file grew more than this number of bytes, or this percentage with a % suffix, since the -diff build")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: icecake size [flags] <file.wasm>")
+		flags.PrintDefaults()
+	}
+	// flags are accepted before and after the file name
+	flags.Parse(_args)
+	filename := flags.Arg(0)
+	if filename != "" {
+		flags.Parse(flags.Args()[1:])
+	}
+	if filename == "" || flags.NArg() != 0 {
+		flags.Usage()
+		return fmt.Errorf("a single wasm file is required")
+	}
+	if *maxgrowth != "" && *diff == "" {
+		return fmt.Errorf("-max-growth requires -diff")
+	}
+
+	rep, err := wasmsize.Analyze(filename)
+	if err != nil {
+		return err
+	}
+
+	var previous *wasmsize.Report
+	if *diff != "" {
+		if previous, err = wasmsize.Load(*diff); err != nil {
+			return err
+		}
+		rep.Diff(previous)
+	}
+
+	if *asjson {
+		if err := rep.WriteJSON(os.Stdout, *top); err != nil {
+			return err
+		}
+	} else {
+		rep.WriteTable(os.Stdout, *top, previous != nil)
+	}
+
+	if *maxgrowth != "" {
+		return checkGrowth(rep.Delta, previous.Size, *maxgrowth)
+	}
+	return nil
+}
+
+// checkGrowth returns an error if _delta exceeds _max, a number of bytes or a percentage of _previous with a % suffix
+func checkGrowth(_delta int, _previous int, _max string) error {
+	limit := strings.Trim(_max, " ")
+	percent := strings.HasSuffix(limit, "%")
+	max, err := strconv.ParseFloat(strings.TrimSuffix(limit, "%"), 64)
+	if err != nil || max < 0 {
+		return fmt.Errorf("invalid -max-growth %q, bytes or percentage expected", _max)
+	}
+	if percent {
+		max = max * float64(_previous) / 100
+	}
+	if float64(_delta) > max {
+		return fmt.Errorf("size regression: the file grew by %d bytes, more than %s", _delta, limit)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+//go:build embedstatic
+
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"log"
+)
+
+// staticfiles embeds the spa static files into the binary.
+// They're copied into ./static before building with the embedstatic tag, see the build_single task.
+//
+//go:embed all:static
+var staticfiles embed.FS
+
+// embeddedStatic returns the file system of the embedded spa static files.
+func embeddedStatic() fs.FS {
+	fsys, err := fs.Sub(staticfiles, "static")
+	if err != nil {
+		log.Fatalf("Error loading embedded static files: %s", err)
+	}
+	return fsys
+}
@@ -0,0 +1,11 @@
+//go:build !embedstatic
+
+package main
+
+import "io/fs"
+
+// embeddedStatic returns nil without the embedstatic build tag,
+// so static files are served from the SPA_STATICFILEDIR directory.
+func embeddedStatic() fs.FS {
+	return nil
+}
@@ -76,15 +76,14 @@ func OnClickBtn2(event *ick.MouseEvent, target *ick.Element) {
 	// instantiate the Notify component and init its data
 	notif := &ui.Notify{
 		Timeout: time.Second * 7,
-		Message: `This message will be automatically removed in <strong><span id="{{.Id}}-timeleft"></span> seconds</strong>, unless you close it before. 😀`,
+		Message: `This message will be automatically removed in <strong><span class="timeleft"></span> seconds</strong>, unless you close it before. 😀`,
 	}
 	notif.MountClasses = ick.ParseClasses("is-danger is-light")
 	notif.MountAttributes, _ = ick.ParseAttributes("role='alert'")
 	notif.UpdateUI = func(uicomponent any) {
 		uinotify := uicomponent.(*ui.Notify)
 		s := math.Round(uinotify.TimeLeft().Seconds())
-		idtl := uinotify.Id() + "-timeleft"
-		webapp.ChildById(idtl).RenderValue("%v", s)
+		uinotify.SelectorQueryFirst(".timeleft").RenderValue("%v", s)
 	}
 
 	// Insert the component into the DOM
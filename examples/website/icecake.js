@@ -66,3 +66,18 @@ function isQuotaExceeded(e) {
     return quotaExceeded;
 }
 
+
+
+/******************************************************************************
+ * Custom Elements
+ */
+
+// ickDefineElement defines the name custom element, calling the go bridge on its lifecycle callbacks
+function ickDefineElement(name, observed, bridge) {
+    customElements.define(name, class extends HTMLElement {
+        static get observedAttributes() { return observed; }
+        connectedCallback() { bridge("connected", this); }
+        disconnectedCallback() { bridge("disconnected", this); }
+        attributeChangedCallback(attr, oldValue, newValue) { bridge("attributeChanged", this, attr, oldValue, newValue); }
+    });
+}
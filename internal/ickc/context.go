@@ -0,0 +1,270 @@
+package ickc
+
+import (
+	"fmt"
+	"strings"
+)
+
+/******************************************************************************
+* html contexts
+******************************************************************************/
+
+// htmlState is where the text written by a template stands within the html
+type htmlState int
+
+const (
+	stateText        htmlState = iota // html text
+	stateTag                          // within a tag, before an attribute name
+	stateAttrName                     // within an attribute name
+	stateAfterName                    // after an attribute name, before its = sign
+	stateBeforeValue                  // after the = sign of an attribute, before its value
+	stateValue                        // within an attribute value
+	stateRawText                      // within the text of a raw text element, like a script
+	stateComment                      // within an html comment
+)
+
+// urlPart is where the text written by a template stands within a url attribute value
+type urlPart int
+
+const (
+	urlStart     urlPart = iota // nothing written yet, the scheme may follow
+	urlPath                     // after the start of the url, before its query
+	urlQuery                    // within the query or the fragment of the url
+	urlAmbiguous                // depends on the branch taken at runtime
+)
+
+// htmlContext is the html context of the text written by a template
+type htmlContext struct {
+	state htmlState
+	tag   string  // the element of the tag or of the raw text, empty within a closing tag
+	attr  string  // the attribute of the attribute name or value
+	quote byte    // the quote of the attribute value, 0 if unquoted
+	url   urlPart // the part of the attribute value if it's a url
+}
+
+// elements whose text is not parsed as html, up to their closing tag
+var rawTextElements = map[string]bool{
+	"script": true, "style": true, "textarea": true, "title": true, "xmp": true,
+	"iframe": true, "noembed": true, "noframes": true, "noscript": true,
+}
+
+// attributes whose value is a url
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true, "poster": true, "background": true,
+	"data": true, "codebase": true, "longdesc": true, "manifest": true, "ping": true, "icon": true, "usemap": true,
+	"xlink:href": true,
+}
+
+const htmlSpaces = " \t\n\r\f"
+
+// scan returns the context following the _text written in the _ctx context
+func (_ctx htmlContext) scan(_text string) htmlContext {
+	for s := _text; len(s) > 0; {
+		switch _ctx.state {
+		case stateText:
+			i := strings.IndexByte(s, '<')
+			if i == -1 {
+				return _ctx
+			}
+			s = s[i+1:]
+			switch {
+			case strings.HasPrefix(s, "!--"):
+				_ctx, s = htmlContext{state: stateComment}, s[3:]
+			case len(s) > 1 && s[0] == '/' && isLetter(s[1]):
+				name := readName(s[1:])
+				_ctx, s = htmlContext{state: stateTag}, s[1+len(name):]
+			case len(s) > 0 && isLetter(s[0]):
+				name := readName(s)
+				_ctx, s = htmlContext{state: stateTag, tag: strings.ToLower(name)}, s[len(name):]
+			case len(s) > 0 && (s[0] == '!' || s[0] == '?'):
+				// doctype or processing instruction
+				_ctx, s = htmlContext{state: stateTag}, s[1:]
+			}
+
+		case stateTag:
+			s = strings.TrimLeft(s, htmlSpaces+"/")
+			switch {
+			case s == "":
+			case s[0] == '>':
+				s = s[1:]
+				if rawTextElements[_ctx.tag] {
+					_ctx = htmlContext{state: stateRawText, tag: _ctx.tag}
+				} else {
+					_ctx = htmlContext{}
+				}
+			default:
+				_ctx.state, _ctx.attr = stateAttrName, ""
+			}
+
+		case stateAttrName:
+			i := strings.IndexAny(s, htmlSpaces+"/=>")
+			if i == -1 {
+				_ctx.attr += strings.ToLower(s)
+				return _ctx
+			}
+			_ctx.attr += strings.ToLower(s[:i])
+			_ctx.state, s = stateAfterName, s[i:]
+
+		case stateAfterName:
+			s = strings.TrimLeft(s, htmlSpaces)
+			if s != "" {
+				if s[0] == '=' {
+					_ctx.state, s = stateBeforeValue, s[1:]
+				} else {
+					_ctx.state = stateTag
+				}
+			}
+
+		case stateBeforeValue:
+			s = strings.TrimLeft(s, htmlSpaces)
+			if s != "" {
+				_ctx.url = urlStart
+				switch s[0] {
+				case '"', '\'':
+					_ctx.state, _ctx.quote, s = stateValue, s[0], s[1:]
+				case '>':
+					_ctx.state = stateTag
+				default:
+					_ctx.state, _ctx.quote = stateValue, 0
+				}
+			}
+
+		case stateValue:
+			var i int
+			if _ctx.quote != 0 {
+				i = strings.IndexByte(s, _ctx.quote)
+			} else {
+				i = strings.IndexAny(s, htmlSpaces+">")
+			}
+			value := s
+			if i != -1 {
+				value = s[:i]
+			}
+			if strings.ContainsAny(value, "?#") {
+				_ctx.url = urlQuery
+			} else if value != "" && _ctx.url == urlStart {
+				_ctx.url = urlPath
+			}
+			if i == -1 {
+				return _ctx
+			}
+			if _ctx.quote != 0 {
+				i++
+			}
+			_ctx, s = htmlContext{state: stateTag, tag: _ctx.tag}, s[i:]
+
+		case stateRawText:
+			i := indexFold(s, "</"+_ctx.tag)
+			if i == -1 {
+				return _ctx
+			}
+			_ctx, s = htmlContext{state: stateTag}, s[i+2+len(_ctx.tag):]
+
+		case stateComment:
+			i := strings.Index(s, "-->")
+			if i == -1 {
+				return _ctx
+			}
+			_ctx, s = htmlContext{}, s[i+3:]
+		}
+	}
+	return _ctx
+}
+
+// printed returns the context following a value printed in the _ctx context
+func (_ctx htmlContext) printed() htmlContext {
+	if _ctx.state == stateValue && _ctx.url == urlStart {
+		_ctx.url = urlPath
+	}
+	return _ctx
+}
+
+// isURL reports whether the context is a quoted url attribute value
+func (_ctx htmlContext) isURL() bool {
+	return _ctx.state == stateValue && _ctx.quote != 0 && urlAttributes[_ctx.attr]
+}
+
+// unprintable returns where values can't be printed in the context, or an empty string if they can:
+// in html text and in quoted attribute values, except event handlers, styles and ambiguous urls.
+func (_ctx htmlContext) unprintable() string {
+	switch _ctx.state {
+	case stateText:
+		return ""
+	case stateTag, stateAfterName:
+		return "within a tag"
+	case stateBeforeValue:
+		return fmt.Sprintf("within the unquoted %s attribute value", _ctx.attr)
+	case stateAttrName:
+		return "within an attribute name"
+	case stateRawText:
+		if _ctx.tag == "script" || _ctx.tag == "style" {
+			return fmt.Sprintf("within a <%s> element", _ctx.tag)
+		}
+		return ""
+	case stateComment:
+		return "within an html comment"
+	}
+	switch {
+	case _ctx.quote == 0:
+		return fmt.Sprintf("within the unquoted %s attribute value", _ctx.attr)
+	case strings.HasPrefix(_ctx.attr, "on"):
+		return fmt.Sprintf("within the %s event handler", _ctx.attr)
+	case _ctx.attr == "style" || _ctx.attr == "srcdoc":
+		return fmt.Sprintf("within the %s attribute", _ctx.attr)
+	case urlAttributes[_ctx.attr] && _ctx.url == urlAmbiguous:
+		return fmt.Sprintf("within the %s url, at a different part of the url depending on the branches", _ctx.attr)
+	}
+	return ""
+}
+
+// joinContexts returns the context following branches ending with the _a and _b contexts,
+// false if the branches end in different contexts
+func joinContexts(_a htmlContext, _b htmlContext) (htmlContext, bool) {
+	if _a == _b {
+		return _a, true
+	}
+	a, b := _a, _b
+	a.url, b.url = urlStart, urlStart
+	if a == b {
+		a.url = urlAmbiguous
+		return a, true
+	}
+	return _a, false
+}
+
+// String describes the context for error messages
+func (_ctx htmlContext) String() string {
+	switch _ctx.state {
+	case stateText:
+		return "html text"
+	case stateRawText:
+		return fmt.Sprintf("a <%s> element", _ctx.tag)
+	case stateComment:
+		return "an html comment"
+	case stateValue:
+		return fmt.Sprintf("the %s attribute value", _ctx.attr)
+	}
+	return "a tag"
+}
+
+func isLetter(_c byte) bool {
+	return _c >= 'a' && _c <= 'z' || _c >= 'A' && _c <= 'Z'
+}
+
+// readName returns the tag name at the start of _s
+func readName(_s string) string {
+	if i := strings.IndexAny(_s, htmlSpaces+"/>"); i != -1 {
+		return _s[:i]
+	}
+	return _s
+}
+
+// indexFold returns the index of the first case-insensitive occurrence of the ascii _substr in _s, or -1
+func indexFold(_s string, _substr string) int {
+	for i := 0; i+len(_substr) <= len(_s); i++ {
+		if strings.EqualFold(_s[i:i+len(_substr)], _substr) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,959 @@
+// Package ickc compiles the .ick.html component templates into go render functions.
+//
+// A template is written with the text/template syntax, and is executed with the ick.TemplateData,
+// the component being .Me. The compiler type-checks every field, method and variable reference
+// against the component struct, and reports errors with their file:line:col positions.
+//
+// Printed values are html escaped, except the ick.HTML ones which are trusted. The compiler follows the html
+// context of the template: values printed at the start of a url attribute are checked against unsafe schemes,
+// the ones printed further in the url are url escaped. Printing a value within a script, a style, an event handler,
+// a style attribute, an unquoted attribute value or a tag is a compile error.
+//
+// The compiled template of the user_card.ick.html file is the RenderHTML method of the UserCard component,
+// generated into the user_card_ick.go file.
+package ickc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+const (
+	TEMPLATE_EXT     = ".ick.html" // the extension of the component template files
+	GENERATED_SUFFIX = "_ick.go"   // the suffix of the generated go files
+
+	ick_path = "github.com/sunraylab/icecake/pkg/icecake"
+)
+
+// the functions supported by the compiler, a subset of the text/template ones
+var builtins = map[string]any{
+	"and": true, "or": true, "not": true,
+	"len": true, "index": true,
+	"print": true, "printf": true, "println": true,
+	"html": true, "urlquery": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true,
+}
+
+// Template is a component template to compile
+type Template struct {
+	File   string       // the template file name, used in error positions
+	Source string       // the template source
+	Type   *types.Named // the component type, the .Me of the template
+}
+
+// GeneratedFile returns the name of the go file generated for the _template file
+func GeneratedFile(_template string) string {
+	return strings.TrimSuffix(_template, TEMPLATE_EXT) + GENERATED_SUFFIX
+}
+
+// ComponentName returns the name of the component type of the _template file, ie. "UserCard" for "user_card.ick.html"
+func ComponentName(_template string) string {
+	base := strings.TrimSuffix(filepath.Base(_template), TEMPLATE_EXT)
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(base, func(r rune) bool { return r == '_' || r == '-' || r == '.' }) {
+		name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return name.String()
+}
+
+// Compile compiles the _tmpl template into the RenderHTML method of its component type,
+// and returns the formatted go file. Every template error is returned with its file:line:col position.
+func Compile(_pkg *types.Package, _tmpl Template) (_code []byte, _err error) {
+	trees, err := parse.Parse(_tmpl.File, _tmpl.Source, "{{", "}}", builtins)
+	if err != nil {
+		return nil, errors.New(strings.TrimPrefix(err.Error(), "template: "))
+	}
+	tree := trees[_tmpl.File]
+	for name, t := range trees {
+		if name != _tmpl.File {
+			loc, _ := t.ErrorContext(t.Root)
+			return nil, fmt.Errorf("%s: {{define %q}} is not supported by the compiler", loc, name)
+		}
+	}
+
+	c := &compiler{
+		tree:    tree,
+		pkg:     _pkg,
+		me:      types.NewPointer(_tmpl.Type),
+		imports: map[string]string{"fmt": "fmt", "strings": "strings", ick_path: "ick"},
+	}
+	c.root = types.NewStruct([]*types.Var{
+		types.NewField(0, nil, "Id", types.Typ[types.String], false),
+		types.NewField(0, nil, "Me", c.me, false),
+		types.NewField(0, nil, "App", types.NewInterfaceType(nil, nil), false),
+	}, nil)
+	root := value{expr: "_data", typ: c.root}
+	c.vars = []variable{{name: "$", value: root}}
+	c.indent = 1
+	if tree.Root != nil {
+		c.walk(tree.Root, root)
+	}
+	if len(c.errs) > 0 {
+		return nil, errors.Join(c.errs...)
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by \"icecake gen templates\" from %s. DO NOT EDIT.\n\n", filepath.Base(_tmpl.File))
+	fmt.Fprintf(&out, "package %s\n\n", _pkg.Name())
+	out.WriteString("import (\n")
+	paths := make([]string, 0, len(c.imports))
+	for path := range c.imports {
+		paths = append(paths, path)
+	}
+	// standard packages first
+	sort.Slice(paths, func(i, j int) bool {
+		istd, jstd := isStd(paths[i]), isStd(paths[j])
+		if istd != jstd {
+			return istd
+		}
+		return paths[i] < paths[j]
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			out.WriteString("\n")
+		}
+		if name := c.imports[path]; name != filepath.Base(path) {
+			fmt.Fprintf(&out, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+	}
+	out.WriteString(")\n\n")
+	fmt.Fprintf(&out, "// RenderHTML renders the compiled %s template, executed with the component as .Me\n", filepath.Base(_tmpl.File))
+	fmt.Fprintf(&out, "func (c *%s) RenderHTML(_data ick.TemplateData) (_html string, _err error) {\n", _tmpl.Type.Obj().Name())
+	out.WriteString("\tdefer func() {\n\t\tif r := recover(); r != nil {\n")
+	fmt.Fprintf(&out, "\t\t\t_err = fmt.Errorf(\"%%s: %%v\", %q, r)\n", filepath.Base(_tmpl.File))
+	out.WriteString("\t\t}\n\t}()\n\tvar _b strings.Builder\n")
+	out.Write(c.body.Bytes())
+	out.WriteString("\treturn _b.String(), nil\n}\n")
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: unable to format the generated code: %w", _tmpl.File, err)
+	}
+	return code, nil
+}
+
+/******************************************************************************
+* compiler
+******************************************************************************/
+
+// value is a compiled expression with its static type, an untyped basic type for constants
+type value struct {
+	expr string
+	typ  types.Type
+	safe bool // the expression is already escaped, like the result of the html function
+}
+
+var invalid = value{expr: "nil", typ: types.Typ[types.Invalid]}
+
+func (v value) isInvalid() bool {
+	return v.typ == types.Typ[types.Invalid]
+}
+
+// variable is a template variable in scope
+type variable struct {
+	name string // the template name, ie. "$x"
+	value
+}
+
+type compiler struct {
+	tree    *parse.Tree
+	pkg     *types.Package
+	me      types.Type        // the pointer to the component type
+	root    *types.Struct     // the type of the root dot, the TemplateData with a typed Me
+	imports map[string]string // the packages imported by the generated code, by path
+	vars    []variable        // the variables in scope, the innermost last
+	nvars   int
+	body    bytes.Buffer
+	ctx     htmlContext // the html context of the text written so far
+	indent  int
+	errs    []error
+}
+
+// errorf records a compile error at the _node position, and returns the invalid value
+func (c *compiler) errorf(_node parse.Node, _format string, _args ...any) value {
+	loc, _ := c.tree.ErrorContext(_node)
+	c.errs = append(c.errs, fmt.Errorf("%s: %s", loc, fmt.Sprintf(_format, _args...)))
+	return invalid
+}
+
+// emit writes a line of go code into the body
+func (c *compiler) emit(_format string, _args ...any) {
+	c.body.WriteString(strings.Repeat("\t", c.indent))
+	fmt.Fprintf(&c.body, _format, _args...)
+	c.body.WriteString("\n")
+}
+
+// emitCheck writes the error check of a runtime call, with the _node position in the template file
+func (c *compiler) emitCheck(_node parse.Node) {
+	loc, _ := c.tree.ErrorContext(_node)
+	loc = filepath.Base(c.tree.ParseName) + strings.TrimPrefix(loc, c.tree.ParseName)
+	c.emit("if err != nil {")
+	c.emit("\treturn \"\", fmt.Errorf(\"%%s: %%w\", %q, err)", loc)
+	c.emit("}")
+}
+
+// newVar returns a new go variable name
+func (c *compiler) newVar(_prefix string) string {
+	c.nvars++
+	return _prefix + strconv.Itoa(c.nvars)
+}
+
+// assign stores _v into a new go variable unless it's already one
+func (c *compiler) assign(_v value) value {
+	if _v.isInvalid() || strings.HasPrefix(_v.expr, "v") && isIdent(_v.expr) {
+		return _v
+	}
+	name := c.newVar("v")
+	c.emit("%s := %s", name, _v.expr)
+	return value{expr: name, typ: types.Default(_v.typ), safe: _v.safe}
+}
+
+// isStd reports whether _path is a standard package, without a domain name
+func isStd(_path string) bool {
+	first, _, _ := strings.Cut(_path, "/")
+	return !strings.Contains(first, ".")
+}
+
+func isIdent(_s string) bool {
+	for _, r := range _s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return _s != ""
+}
+
+// qualifier names the types of other packages in the generated code, importing them
+func (c *compiler) qualifier(_pkg *types.Package) string {
+	if _pkg == c.pkg {
+		return ""
+	}
+	c.imports[_pkg.Path()] = _pkg.Name()
+	return _pkg.Name()
+}
+
+// typeString returns the type name for error messages
+func (c *compiler) typeString(_typ types.Type) string {
+	if _typ == c.root {
+		return "ick.TemplateData"
+	}
+	return types.TypeString(_typ, types.RelativeTo(c.pkg))
+}
+
+/******************************************************************************
+* statements
+******************************************************************************/
+
+func (c *compiler) walk(_node parse.Node, _dot value) {
+	switch n := _node.(type) {
+	case *parse.ListNode:
+		for _, item := range n.Nodes {
+			c.walk(item, _dot)
+		}
+	case *parse.TextNode:
+		if len(n.Text) > 0 {
+			c.emit("_b.WriteString(%s)", strconv.Quote(string(n.Text)))
+			c.ctx = c.ctx.scan(string(n.Text))
+		}
+	case *parse.CommentNode:
+	case *parse.ActionNode:
+		v := c.pipeline(n.Pipe, _dot, true)
+		if len(n.Pipe.Decl) == 0 {
+			c.printAction(n, v)
+		}
+	case *parse.IfNode:
+		c.branch(&n.BranchNode, _dot, false)
+	case *parse.WithNode:
+		c.branch(&n.BranchNode, _dot, true)
+	case *parse.RangeNode:
+		c.rangeNode(n, _dot)
+	case *parse.BreakNode:
+		c.emit("break")
+	case *parse.ContinueNode:
+		c.emit("continue")
+	case *parse.TemplateNode:
+		c.errorf(n, "{{template %q}} is not supported by the compiler", n.Name)
+	default:
+		c.errorf(_node, "unexpected %s", _node)
+	}
+}
+
+// printAction prints the _v value of the _action escaped for the html context,
+// or reports an error if values can't be printed in this context
+func (c *compiler) printAction(_action *parse.ActionNode, _v value) {
+	if _v.isInvalid() {
+		return
+	}
+	if where := c.ctx.unprintable(); where != "" {
+		c.errorf(_action, "can't print %s %s, values are printed in html text and quoted attribute values only", _action, where)
+		return
+	}
+	if c.ctx.isURL() {
+		c.printURL(_v)
+	} else {
+		c.print(_v)
+	}
+	c.ctx = c.ctx.printed()
+}
+
+// print writes the value the way text/template prints it, escaped unless it's a trusted ick.HTML
+// or an already escaped value. Numbers and booleans never need to be escaped.
+func (c *compiler) print(_v value) {
+	if _v.isInvalid() {
+		return
+	}
+	switch {
+	case _v.safe:
+		c.emit("_b.WriteString(%s)", _v.expr)
+	case isTrustedHTML(_v.typ):
+		c.emit("_b.WriteString(string(%s))", _v.expr)
+	case types.Identical(types.Default(_v.typ), types.Typ[types.String]):
+		c.imports["html"] = "html"
+		c.emit("_b.WriteString(html.EscapeString(%s))", _v.expr)
+	case isBasic(_v.typ, types.IsNumeric|types.IsBoolean):
+		c.emit("fmt.Fprint(&_b, %s)", _v.expr)
+	default:
+		c.imports["html"] = "html"
+		c.emit("_b.WriteString(html.EscapeString(fmt.Sprint(%s)))", _v.expr)
+	}
+}
+
+// printURL writes the value within a url attribute. At the start of the url, urls with an unsafe scheme like
+// "javascript:" are replaced by sanitize.UNSAFE_URL. Further in the url the value is url escaped,
+// unless it's already escaped. Trusted ick.HTML values are not trusted as urls.
+func (c *compiler) printURL(_v value) {
+	str := _v.expr
+	switch {
+	case isTrustedHTML(_v.typ):
+		str = "string(" + _v.expr + ")"
+	case !types.Identical(types.Default(_v.typ), types.Typ[types.String]):
+		str = "fmt.Sprint(" + _v.expr + ")"
+	}
+	c.imports["html"] = "html"
+	switch {
+	case c.ctx.url == urlStart && _v.safe:
+		c.emit("_b.WriteString(ick.TemplateURL(%s))", str)
+	case c.ctx.url == urlStart:
+		c.emit("_b.WriteString(html.EscapeString(ick.TemplateURL(%s)))", str)
+	case _v.safe:
+		c.emit("_b.WriteString(%s)", str)
+	case c.ctx.url == urlPath:
+		c.imports["net/url"] = "url"
+		c.emit("_b.WriteString(html.EscapeString(url.PathEscape(%s)))", str)
+	default:
+		c.imports["net/url"] = "url"
+		c.emit("_b.WriteString(url.QueryEscape(%s))", str)
+	}
+}
+
+// isTrustedHTML reports whether _typ is the ick.HTML type, or the html/template.HTML type it's an alias of.
+// The alias is kept by the type checker depending on the go version, both named and alias types have an Obj.
+func isTrustedHTML(_typ types.Type) bool {
+	named, ok := _typ.(interface{ Obj() *types.TypeName })
+	if !ok || named.Obj().Pkg() == nil || named.Obj().Name() != "HTML" {
+		return false
+	}
+	path := named.Obj().Pkg().Path()
+	return path == "html/template" || path == ick_path
+}
+
+// isBasic reports whether the underlying type of _typ is a basic type with the _info properties
+func isBasic(_typ types.Type, _info types.BasicInfo) bool {
+	basic, ok := _typ.Underlying().(*types.Basic)
+	return ok && basic.Info()&_info != 0
+}
+
+// branch compiles {{if}} and {{with}}, the dot of the {{with}} body is the pipeline value
+func (c *compiler) branch(_branch *parse.BranchNode, _dot value, _with bool) {
+	mark := len(c.vars)
+	v := c.pipeline(_branch.Pipe, _dot, true)
+	if _with {
+		v = c.assign(v)
+	}
+	c.emit("if %s {", c.truth(v))
+	c.indent++
+	start := c.ctx
+	if _with {
+		c.walk(_branch.List, v)
+	} else {
+		c.walk(_branch.List, _dot)
+	}
+	end := c.ctx
+	c.ctx = start
+	c.indent--
+	if _branch.ElseList != nil {
+		c.emit("} else {")
+		c.indent++
+		c.walk(_branch.ElseList, _dot)
+		c.indent--
+	}
+	c.emit("}")
+	c.vars = c.vars[:mark]
+	c.joinContext(_branch, end)
+}
+
+// joinContext joins the _end context of a branch with the current context, the end of the other branch,
+// and reports an error if they are different
+func (c *compiler) joinContext(_node parse.Node, _end htmlContext) {
+	ctx, ok := joinContexts(_end, c.ctx)
+	if !ok {
+		c.errorf(_node, "the branches end in different html contexts: %s and %s", _end, c.ctx)
+	}
+	c.ctx = ctx
+}
+
+// rangeNode compiles {{range}} over slices, arrays, maps sorted by keys, and channels
+func (c *compiler) rangeNode(_range *parse.RangeNode, _dot value) {
+	mark := len(c.vars)
+	defer func() { c.vars = c.vars[:mark] }()
+
+	v := c.pipeline(_range.Pipe, _dot, false)
+	if v.isInvalid() {
+		return
+	}
+	decl := _range.Pipe.Decl
+	var keytyp, elemtyp types.Type
+	switch typ := v.typ.Underlying().(type) {
+	case *types.Slice:
+		keytyp, elemtyp = types.Typ[types.Int], typ.Elem()
+	case *types.Array:
+		keytyp, elemtyp = types.Typ[types.Int], typ.Elem()
+	case *types.Map:
+		keytyp, elemtyp = typ.Key(), typ.Elem()
+		if basic, ok := keytyp.Underlying().(*types.Basic); !ok || basic.Info()&types.IsOrdered == 0 {
+			c.errorf(_range, "can't range over %s, map keys must be ordered to be sorted", c.typeString(v.typ))
+			return
+		}
+	case *types.Chan:
+		elemtyp = typ.Elem()
+		if len(decl) > 1 {
+			c.errorf(_range, "can't use a key variable ranging over the channel %s", c.typeString(v.typ))
+			return
+		}
+	default:
+		c.errorf(_range, "can't range over %s, a slice, an array, a map or a channel is expected", c.typeString(v.typ))
+		return
+	}
+
+	coll := c.assign(v)
+	key, elem := c.newVar("k"), c.newVar("e")
+	start, elseEnd := c.ctx, c.ctx
+	counter := ""
+	_, ischan := v.typ.Underlying().(*types.Chan)
+	if _range.ElseList != nil {
+		if ischan {
+			counter = c.newVar("n")
+			c.emit("%s := 0", counter)
+		} else {
+			c.emit("if len(%s) == 0 {", coll.expr)
+			c.indent++
+			c.walk(_range.ElseList, _dot)
+			elseEnd, c.ctx = c.ctx, start
+			c.indent--
+			c.emit("} else {")
+			c.indent++
+		}
+	}
+
+	switch typ := v.typ.Underlying().(type) {
+	case *types.Map:
+		keys := c.newVar("keys")
+		c.imports["sort"] = "sort"
+		c.emit("%s := make([]%s, 0, len(%s))", keys, types.TypeString(typ.Key(), c.qualifier), coll.expr)
+		c.emit("for k := range %s {", coll.expr)
+		c.emit("\t%s = append(%s, k)", keys, keys)
+		c.emit("}")
+		c.emit("sort.Slice(%s, func(i, j int) bool { return %s[i] < %s[j] })", keys, keys, keys)
+		c.emit("for _, %s := range %s {", key, keys)
+		c.emit("\t%s := %s[%s]", elem, coll.expr, key)
+	case *types.Chan:
+		c.emit("for %s := range %s {", elem, coll.expr)
+	default:
+		c.emit("for %s, %s := range %s {", key, elem, coll.expr)
+	}
+	c.indent++
+	if keytyp != nil {
+		c.emit("_, _ = %s, %s", key, elem)
+	} else {
+		c.emit("_ = %s", elem)
+	}
+	if counter != "" {
+		c.emit("%s++", counter)
+	}
+	elemv := value{expr: elem, typ: elemtyp}
+	switch len(decl) {
+	case 1:
+		c.vars = append(c.vars, variable{name: decl[0].Ident[0], value: elemv})
+	case 2:
+		c.vars = append(c.vars, variable{name: decl[0].Ident[0], value: value{expr: key, typ: keytyp}})
+		c.vars = append(c.vars, variable{name: decl[1].Ident[0], value: elemv})
+	}
+	c.walk(_range.List, elemv)
+	c.indent--
+	c.emit("}")
+	// the body may be executed any number of times
+	c.joinContext(_range, start)
+
+	if _range.ElseList != nil {
+		if ischan {
+			c.emit("if %s == 0 {", counter)
+			c.indent++
+			body := c.ctx
+			c.ctx = start
+			c.walk(_range.ElseList, _dot)
+			elseEnd, c.ctx = c.ctx, body
+			c.indent--
+		} else {
+			c.indent--
+		}
+		c.emit("}")
+		c.joinContext(_range, elseEnd)
+	}
+}
+
+/******************************************************************************
+* expressions
+******************************************************************************/
+
+// pipeline evaluates the _pipe commands, each result being the last argument of the next command.
+// The pipeline variables are declared or assigned if _decl is set.
+func (c *compiler) pipeline(_pipe *parse.PipeNode, _dot value, _decl bool) value {
+	var final *value
+	var v value
+	for _, cmd := range _pipe.Cmds {
+		v = c.command(cmd, _dot, final)
+		final = &v
+	}
+	if !_decl || len(_pipe.Decl) == 0 {
+		return v
+	}
+	if len(_pipe.Decl) > 1 {
+		return c.errorf(_pipe, "too many declarations in %s", _pipe)
+	}
+	name := _pipe.Decl[0].Ident[0]
+	if v.isInvalid() {
+		// declared anyway to not report undefined variables
+		if !_pipe.IsAssign {
+			c.vars = append(c.vars, variable{name: name, value: v})
+		}
+		return v
+	}
+	if _pipe.IsAssign {
+		target := c.lookupVar(_pipe.Decl[0], name)
+		if target.isInvalid() {
+			return target
+		}
+		if !c.assignable(v, target.typ) {
+			return c.errorf(_pipe, "can't assign %s to %s of type %s", c.typeString(v.typ), name, c.typeString(target.typ))
+		}
+		c.emit("%s = %s", target.expr, v.expr)
+		return target
+	}
+	gov := c.newVar("v")
+	c.emit("%s := %s", gov, v.expr)
+	c.emit("_ = %s", gov)
+	declared := value{expr: gov, typ: types.Default(v.typ), safe: v.safe}
+	c.vars = append(c.vars, variable{name: name, value: declared})
+	return declared
+}
+
+// command evaluates a command of a pipeline, _final is the result of the previous command if any
+func (c *compiler) command(_cmd *parse.CommandNode, _dot value, _final *value) value {
+	switch n := _cmd.Args[0].(type) {
+	case *parse.FieldNode:
+		return c.fields(_dot, n.Ident, _cmd.Args[1:], _final, _dot, n)
+	case *parse.ChainNode:
+		return c.fields(c.arg(n.Node, _dot), n.Field, _cmd.Args[1:], _final, _dot, n)
+	case *parse.VariableNode:
+		v := c.lookupVar(n, n.Ident[0])
+		if len(n.Ident) == 1 {
+			if len(_cmd.Args) > 1 || _final != nil {
+				return c.errorf(n, "can't give arguments to the variable %s", n.Ident[0])
+			}
+			return v
+		}
+		return c.fields(v, n.Ident[1:], _cmd.Args[1:], _final, _dot, n)
+	case *parse.IdentifierNode:
+		return c.function(n, _cmd.Args[1:], _final, _dot)
+	}
+	if len(_cmd.Args) > 1 || _final != nil {
+		return c.errorf(_cmd, "can't give arguments to the non-function %s", _cmd.Args[0])
+	}
+	return c.arg(_cmd.Args[0], _dot)
+}
+
+// arg evaluates a single operand
+func (c *compiler) arg(_node parse.Node, _dot value) value {
+	switch n := _node.(type) {
+	case *parse.DotNode:
+		return _dot
+	case *parse.NilNode:
+		return value{expr: "nil", typ: types.Typ[types.UntypedNil]}
+	case *parse.BoolNode:
+		return value{expr: strconv.FormatBool(n.True), typ: types.Typ[types.UntypedBool]}
+	case *parse.StringNode:
+		return value{expr: strconv.Quote(n.Text), typ: types.Typ[types.UntypedString]}
+	case *parse.NumberNode:
+		switch {
+		case n.IsInt || n.IsUint:
+			return value{expr: n.Text, typ: types.Typ[types.UntypedInt]}
+		case n.IsFloat:
+			return value{expr: n.Text, typ: types.Typ[types.UntypedFloat]}
+		}
+		return c.errorf(n, "complex constants are not supported by the compiler")
+	case *parse.FieldNode:
+		return c.fields(_dot, n.Ident, nil, nil, _dot, n)
+	case *parse.ChainNode:
+		return c.fields(c.arg(n.Node, _dot), n.Field, nil, nil, _dot, n)
+	case *parse.VariableNode:
+		v := c.lookupVar(n, n.Ident[0])
+		if len(n.Ident) == 1 {
+			return v
+		}
+		return c.fields(v, n.Ident[1:], nil, nil, _dot, n)
+	case *parse.PipeNode:
+		return c.pipeline(n, _dot, true)
+	case *parse.IdentifierNode:
+		return c.function(n, nil, nil, _dot)
+	}
+	return c.errorf(_node, "unexpected operand %s", _node)
+}
+
+func (c *compiler) lookupVar(_node parse.Node, _name string) value {
+	for i := len(c.vars) - 1; i >= 0; i-- {
+		if c.vars[i].name == _name {
+			return c.vars[i].value
+		}
+	}
+	return c.errorf(_node, "undefined variable %s", _name)
+}
+
+// fields evaluates the chain of _idents fields from the _receiver, _args are given to the last one
+func (c *compiler) fields(_receiver value, _idents []string, _args []parse.Node, _final *value, _dot value, _node parse.Node) value {
+	args := make([]value, 0, len(_args)+1)
+	for _, a := range _args {
+		args = append(args, c.arg(a, _dot))
+	}
+	if _final != nil {
+		args = append(args, *_final)
+	}
+	v := _receiver
+	for i, name := range _idents {
+		if i < len(_idents)-1 {
+			v = c.selector(v, name, nil, _node)
+		} else {
+			v = c.selector(v, name, args, _node)
+		}
+	}
+	return v
+}
+
+// selector evaluates the _name field or method of _v, type-checked against the _v type
+func (c *compiler) selector(_v value, _name string, _args []value, _node parse.Node) value {
+	if _v.isInvalid() {
+		return _v
+	}
+	for _, a := range _args {
+		if a.isInvalid() {
+			return a
+		}
+	}
+
+	// the root dot, with a typed component
+	if _v.typ == c.root {
+		if len(_args) > 0 {
+			return c.errorf(_node, "%s is not a method but has arguments", _name)
+		}
+		switch _name {
+		case "Id":
+			return value{expr: "_data.Id", typ: types.Typ[types.String]}
+		case "Me":
+			return value{expr: "c", typ: c.me}
+		case "App":
+			return value{expr: "_data.App", typ: types.NewInterfaceType(nil, nil)}
+		}
+		return c.errorf(_node, "can't evaluate field %s in type ick.TemplateData", _name)
+	}
+
+	obj, _, _ := types.LookupFieldOrMethod(_v.typ, true, c.pkg, _name)
+
+	// untyped values, evaluated at runtime
+	if iface, ok := _v.typ.Underlying().(*types.Interface); ok && obj == nil {
+		if iface.NumMethods() > 0 {
+			return c.errorf(_node, "can't evaluate field %s in type %s", _name, c.typeString(_v.typ))
+		}
+		if len(_args) > 0 {
+			return c.errorf(_node, "can't call %s with arguments on a value of type %s", _name, c.typeString(_v.typ))
+		}
+		name := c.newVar("v")
+		c.emit("%s, err := ick.TemplateField(%s, %q)", name, _v.expr, _name)
+		c.emitCheck(_node)
+		return value{expr: name, typ: _v.typ}
+	}
+
+	// maps with string keys
+	if m, ok := _v.typ.Underlying().(*types.Map); ok && obj == nil {
+		if basic, ok := m.Key().Underlying().(*types.Basic); ok && basic.Info()&types.IsString != 0 {
+			if len(_args) > 0 {
+				return c.errorf(_node, "%s is a map key but has arguments", _name)
+			}
+			return value{expr: fmt.Sprintf("%s[%q]", _v.expr, _name), typ: m.Elem()}
+		}
+	}
+
+	switch obj := obj.(type) {
+	case *types.Var:
+		if !obj.Exported() {
+			return c.errorf(_node, "%s is an unexported field of struct type %s", _name, c.typeString(_v.typ))
+		}
+		if len(_args) > 0 {
+			return c.errorf(_node, "%s has arguments but cannot be invoked as function", _name)
+		}
+		return value{expr: _v.expr + "." + _name, typ: obj.Type()}
+	case *types.Func:
+		if !obj.Exported() {
+			return c.errorf(_node, "%s is an unexported method of type %s", _name, c.typeString(_v.typ))
+		}
+		return c.call(_v.expr+"."+_name, obj.Type().(*types.Signature), _args, _node)
+	}
+	return c.errorf(_node, "can't evaluate field %s in type %s", _name, c.typeString(_v.typ))
+}
+
+var errorType = types.Universe.Lookup("error").Type()
+
+// call type-checks the method call, returning a single value or a value with an error
+func (c *compiler) call(_fn string, _sig *types.Signature, _args []value, _node parse.Node) value {
+	params := _sig.Params()
+	nparams := params.Len()
+	if _sig.Variadic() && len(_args) < nparams-1 || !_sig.Variadic() && len(_args) != nparams {
+		return c.errorf(_node, "wrong number of args for %s: want %d got %d", _fn, nparams, len(_args))
+	}
+	exprs := make([]string, len(_args))
+	for i, a := range _args {
+		var ptyp types.Type
+		if _sig.Variadic() && i >= nparams-1 {
+			ptyp = params.At(nparams - 1).Type().(*types.Slice).Elem()
+		} else {
+			ptyp = params.At(i).Type()
+		}
+		if !c.assignable(a, ptyp) {
+			return c.errorf(_node, "wrong type for value; expected %s; got %s", c.typeString(ptyp), c.typeString(a.typ))
+		}
+		exprs[i] = a.expr
+	}
+
+	results := _sig.Results()
+	call := _fn + "(" + strings.Join(exprs, ", ") + ")"
+	switch {
+	case results.Len() == 1:
+		return value{expr: call, typ: results.At(0).Type()}
+	case results.Len() == 2 && types.Identical(results.At(1).Type(), errorType):
+		name := c.newVar("v")
+		c.emit("%s, err := %s", name, call)
+		c.emitCheck(_node)
+		return value{expr: name, typ: results.At(0).Type()}
+	}
+	return c.errorf(_node, "can't call %s with %d results", _fn, results.Len())
+}
+
+// function evaluates a builtin function call
+func (c *compiler) function(_ident *parse.IdentifierNode, _args []parse.Node, _final *value, _dot value) value {
+	args := make([]value, 0, len(_args)+1)
+	for _, a := range _args {
+		v := c.arg(a, _dot)
+		if v.isInvalid() {
+			return v
+		}
+		args = append(args, v)
+	}
+	if _final != nil {
+		if _final.isInvalid() {
+			return *_final
+		}
+		args = append(args, *_final)
+	}
+	name := _ident.Ident
+	wantargs := func(_min int, _max int) bool {
+		if len(args) < _min || _max >= 0 && len(args) > _max {
+			c.errorf(_ident, "wrong number of args for %s: got %d", name, len(args))
+			return false
+		}
+		return true
+	}
+	str := types.Typ[types.String]
+	boolean := types.Typ[types.Bool]
+
+	switch name {
+	case "print", "println", "printf":
+		if name == "printf" && wantargs(1, -1) && !c.assignable(args[0], str) {
+			return c.errorf(_ident, "printf format must be a string, got %s", c.typeString(args[0].typ))
+		}
+		return value{expr: "fmt.Sprint" + name[len("print"):] + "(" + joinExprs(args) + ")", typ: str}
+	case "html":
+		c.imports["html"] = "html"
+		return value{expr: "html.EscapeString(" + c.sprint(args) + ")", typ: str, safe: true}
+	case "urlquery":
+		c.imports["net/url"] = "url"
+		return value{expr: "url.QueryEscape(" + c.sprint(args) + ")", typ: str, safe: true}
+	case "not":
+		if !wantargs(1, 1) {
+			return invalid
+		}
+		return value{expr: "(!" + c.truth(args[0]) + ")", typ: boolean}
+	case "and", "or":
+		if !wantargs(1, -1) {
+			return invalid
+		}
+		truths := make([]string, len(args))
+		for i, a := range args {
+			truths[i] = c.truth(a)
+		}
+		op := " && "
+		if name == "or" {
+			op = " || "
+		}
+		return value{expr: "(" + strings.Join(truths, op) + ")", typ: boolean}
+	case "len":
+		if !wantargs(1, 1) {
+			return invalid
+		}
+		switch typ := args[0].typ.Underlying().(type) {
+		case *types.Slice, *types.Array, *types.Map, *types.Chan:
+			return value{expr: "len(" + args[0].expr + ")", typ: types.Typ[types.Int]}
+		case *types.Basic:
+			if typ.Info()&types.IsString != 0 {
+				return value{expr: "len(" + args[0].expr + ")", typ: types.Typ[types.Int]}
+			}
+		}
+		return c.errorf(_ident, "len of type %s", c.typeString(args[0].typ))
+	case "index":
+		if !wantargs(1, -1) {
+			return invalid
+		}
+		v := args[0]
+		for _, key := range args[1:] {
+			switch typ := v.typ.Underlying().(type) {
+			case *types.Slice, *types.Array:
+				if !c.assignable(key, types.Typ[types.Int]) {
+					return c.errorf(_ident, "cannot index %s with %s", c.typeString(v.typ), c.typeString(key.typ))
+				}
+				elem := typ.(interface{ Elem() types.Type }).Elem()
+				v = value{expr: v.expr + "[" + key.expr + "]", typ: elem}
+			case *types.Map:
+				if !c.assignable(key, typ.Key()) {
+					return c.errorf(_ident, "cannot index %s with %s", c.typeString(v.typ), c.typeString(key.typ))
+				}
+				v = value{expr: v.expr + "[" + key.expr + "]", typ: typ.Elem()}
+			default:
+				return c.errorf(_ident, "can't index item of type %s", c.typeString(v.typ))
+			}
+		}
+		return v
+	case "eq", "ne", "lt", "le", "gt", "ge":
+		if name == "eq" && !wantargs(2, -1) || name != "eq" && !wantargs(2, 2) {
+			return invalid
+		}
+		ops := map[string]string{"eq": "==", "ne": "!=", "lt": "<", "le": "<=", "gt": ">", "ge": ">="}
+		conds := make([]string, 0, len(args)-1)
+		for _, b := range args[1:] {
+			if !c.comparable(args[0], b, name == "eq" || name == "ne") {
+				return c.errorf(_ident, "incompatible types for comparison: %s and %s", c.typeString(args[0].typ), c.typeString(b.typ))
+			}
+			conds = append(conds, args[0].expr+" "+ops[name]+" "+b.expr)
+		}
+		return value{expr: "(" + strings.Join(conds, " || ") + ")", typ: boolean}
+	}
+	return c.errorf(_ident, "function %q not supported by the compiler", name)
+}
+
+func joinExprs(_args []value) string {
+	exprs := make([]string, len(_args))
+	for i, a := range _args {
+		exprs[i] = a.expr
+	}
+	return strings.Join(exprs, ", ")
+}
+
+// sprint returns the string of the _args, the way text/template evaluates the arguments of the escaping functions
+func (c *compiler) sprint(_args []value) string {
+	if len(_args) == 1 && types.Identical(types.Default(_args[0].typ), types.Typ[types.String]) {
+		return _args[0].expr
+	}
+	return "fmt.Sprint(" + joinExprs(_args) + ")"
+}
+
+// truth returns the go condition of the _v value, according to the text/template truth
+func (c *compiler) truth(_v value) string {
+	if _v.isInvalid() {
+		return "false"
+	}
+	switch typ := _v.typ.Underlying().(type) {
+	case *types.Basic:
+		switch {
+		case typ.Kind() == types.UntypedNil:
+			return "false"
+		case typ.Info()&types.IsBoolean != 0:
+			return _v.expr
+		case typ.Info()&types.IsString != 0:
+			return "(" + _v.expr + ` != "")`
+		case typ.Info()&types.IsNumeric != 0:
+			return "(" + _v.expr + " != 0)"
+		}
+	case *types.Slice, *types.Map, *types.Array:
+		return "(len(" + _v.expr + ") > 0)"
+	case *types.Pointer, *types.Chan, *types.Signature:
+		return "(" + _v.expr + " != nil)"
+	case *types.Interface:
+		return "ick.TemplateTruth(" + _v.expr + ")"
+	}
+	return "true"
+}
+
+// assignable reports whether _v can be given as a _typ value, untyped constants are checked by kind
+func (c *compiler) assignable(_v value, _typ types.Type) bool {
+	basic, untyped := _v.typ.(*types.Basic)
+	if !untyped || basic.Info()&types.IsUntyped == 0 {
+		return types.AssignableTo(_v.typ, _typ)
+	}
+	switch target := _typ.Underlying().(type) {
+	case *types.Interface:
+		return true
+	case *types.Pointer, *types.Slice, *types.Map, *types.Chan, *types.Signature:
+		return basic.Kind() == types.UntypedNil
+	case *types.Basic:
+		info := target.Info()
+		switch basic.Kind() {
+		case types.UntypedBool:
+			return info&types.IsBoolean != 0
+		case types.UntypedString:
+			return info&types.IsString != 0
+		case types.UntypedInt, types.UntypedRune:
+			return info&types.IsNumeric != 0
+		case types.UntypedFloat:
+			return info&(types.IsFloat|types.IsComplex) != 0
+		}
+	}
+	return false
+}
+
+// comparable reports whether _a and _b can be compared, with == and != if _equality, or ordered otherwise
+func (c *compiler) comparable(_a value, _b value, _equality bool) bool {
+	if !c.assignable(_a, _b.typ) && !c.assignable(_b, _a.typ) {
+		return false
+	}
+	if _equality {
+		return types.Comparable(types.Default(_a.typ)) && types.Comparable(types.Default(_b.typ))
+	}
+	basic, ok := types.Default(_a.typ).Underlying().(*types.Basic)
+	return ok && basic.Info()&types.IsOrdered != 0
+}
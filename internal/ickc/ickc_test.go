@@ -0,0 +1,158 @@
+package ickc
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+// the runtime helpers of the ick package used by the generated code
+const ickStub = `package ick
+import "html/template"
+type HTML = template.HTML
+type TemplateData struct {
+	Id  string
+	Me  any
+	App any
+}
+func TemplateField(_v any, _name string) (any, error) { return nil, nil }
+func TemplateTruth(_v any) bool { return false }
+func TemplateURL(_url string) string { return _url }
+`
+
+const componentSrc = `package cards
+import ick "github.com/sunraylab/icecake/pkg/icecake"
+type Item struct {
+	Label string
+	Price float64
+}
+type UserCard struct {
+	Title  string
+	Count  int
+	Items  []Item
+	Tags   map[string]int
+	Ptr    *Item
+	Intro  ick.HTML
+	secret string
+}
+func (c *UserCard) Greet(who string) (string, error) { return "hi " + who, nil }
+`
+
+// testImporter imports the ick stub, and the standard packages from source
+type testImporter struct {
+	ick *types.Package
+	std types.Importer
+}
+
+func (imp testImporter) Import(_path string) (*types.Package, error) {
+	if _path == ick_path {
+		return imp.ick, nil
+	}
+	return imp.std.Import(_path)
+}
+
+func check(t *testing.T, _fset *token.FileSet, _imp types.Importer, _path string, _srcs ...string) *types.Package {
+	files := make([]*ast.File, 0, len(_srcs))
+	for i, src := range _srcs {
+		file, err := parser.ParseFile(_fset, _path+"/file"+string(rune('0'+i))+".go", src, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, file)
+	}
+	conf := types.Config{Importer: _imp}
+	pkg, err := conf.Check(_path, _fset, files, nil)
+	if err != nil {
+		t.Fatalf("%s: %s", _path, err)
+	}
+	return pkg
+}
+
+func TestCompile(t *testing.T) {
+	fset := token.NewFileSet()
+	imp := testImporter{std: importer.ForCompiler(fset, "source", nil)}
+	imp.ick = check(t, fset, imp, ick_path, ickStub)
+	pkg := check(t, fset, imp, "example.com/cards", componentSrc)
+	typ, err := LookupComponent(pkg, ComponentName("user_card.ick.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		src  string
+		err  string // the expected error, empty if the template compiles
+		code string // a statement expected in the generated code
+	}{
+		{src: `<h1 id="{{.Id}}">{{.Me.Title}}</h1>`, code: "_b.WriteString(html.EscapeString(c.Title))"},
+		{src: `{{.Me.Intro}}{{.Me.Count}}`, code: "_b.WriteString(string(c.Intro))\n\tfmt.Fprint(&_b, c.Count)"},
+		{src: `{{$x := html .Me.Title}}{{$x}}{{.Me.Ptr}}`, code: "_b.WriteString(v1)\n\t_b.WriteString(html.EscapeString(fmt.Sprint(c.Ptr)))"},
+		{src: `{{if gt .Me.Count 0}}{{.Me.Count}}{{else if .Me.Title}}{{.Me.Title | html}}{{end}}`},
+		{src: `{{range $i, $it := .Me.Items}}{{$i}}:{{$it.Label}} {{printf "%.2f" .Price}}{{else}}none{{end}}`},
+		{src: `{{range $k, $v := .Me.Tags}}{{$k}}={{$v}}{{end}}{{with .Me.Ptr}}{{.Label}}{{end}}`},
+		{src: `{{.Me.Greet "bob"}} {{.App.User.Name}} {{if .App}}app{{end}}`},
+		{src: `{{$x := len .Me.Items}}{{$x = 2}}{{if and (eq $x 2 3) (not .Me.Title)}}{{index .Me.Items 0}}{{end}}`},
+		{src: `<a href="{{.Me.Title}}" title='{{.Me.Title}}'>`, code: "_b.WriteString(html.EscapeString(ick.TemplateURL(c.Title)))"},
+		{src: `<a href="{{.Me.Intro}}">`, code: "_b.WriteString(html.EscapeString(ick.TemplateURL(string(c.Intro))))"},
+		{src: `<a href="/users/{{.Me.Count}}?q={{.Me.Title}}">`, code: "url.PathEscape(fmt.Sprint(c.Count))))\n\t_b.WriteString(\"?q=\")\n\t_b.WriteString(url.QueryEscape(c.Title))"},
+		{src: `<textarea>{{.Me.Title}}</textarea><script src="/a.js"></script><!-- x -->{{.Me.Title}}`},
+		{src: `{{range .Me.Items}}<a href="{{if .Label}}{{.Label}}{{else}}/{{end}}">{{.Label}}</a>{{end}}`},
+		{src: `<a onclick="go({{.Me.Title}})">`, err: `can't print {{.Me.Title}} within the onclick event handler`},
+		{src: `<div style="color: {{.Me.Title}}">`, err: "within the style attribute"},
+		{src: `<script>var t = "{{.Me.Title}}"</script>`, err: "within a <script> element"},
+		{src: `<STYLE>p{}{{.Me.Title}}</STYLE>`, err: "within a <style> element"},
+		{src: `<input value={{.Me.Title}}>`, err: "within the unquoted value attribute value"},
+		{src: `<div {{.Me.Title}}>`, err: "within a tag"},
+		{src: `<!-- {{.Me.Title}} -->`, err: "within an html comment"},
+		{src: `<a href="{{if .Me.Count}}/a{{end}}{{.Me.Title}}">`, err: "at a different part of the url"},
+		{src: `{{if .Me.Count}}<p>{{else}}<p class="{{end}}">`, err: "the branches end in different html contexts: html text and the class attribute value"},
+		{src: `{{range .Me.Items}}<p class="{{end}}`, err: "the branches end in different html contexts"},
+		{src: "\n{{.Me.Titel}}", err: "user_card.ick.html:2:5: can't evaluate field Titel in type *UserCard"},
+		{src: `{{.Me.secret}}`, err: "secret is an unexported field of struct type *UserCard"},
+		{src: `{{range .Me.Title}}{{end}}`, err: "can't range over string"},
+		{src: `{{.Me.Greet 3}}`, err: "wrong type for value; expected string; got untyped int"},
+		{src: `{{eq .Me.Count "a"}}`, err: "incompatible types for comparison: int and untyped string"},
+		{src: `{{$x := 1}}{{$x = "s"}}`, err: "can't assign untyped string to $x of type int"},
+		{src: `{{.Me.Title | foo}}`, err: `user_card.ick.html:1: function "foo" not defined`},
+		{src: `{{template "other"}}`, err: `{{template "other"}} is not supported by the compiler`},
+	}
+	for _, tst := range tests {
+		code, err := Compile(pkg, Template{File: "user_card.ick.html", Source: tst.src, Type: typ})
+		if tst.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tst.err) {
+				t.Errorf("%s: error %q expected, got %v", tst.src, tst.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tst.src, err)
+			continue
+		}
+		if tst.code != "" && !strings.Contains(string(code), tst.code) {
+			t.Errorf("%s: %q expected in\n%s", tst.src, tst.code, code)
+		}
+		// the generated code must compile with the component
+		check(t, fset, imp, "example.com/cards", componentSrc, string(code))
+	}
+}
+
+func TestComponentName(t *testing.T) {
+	for file, name := range map[string]string{
+		"notify.ick.html":          "Notify",
+		"web/user_card.ick.html":   "UserCard",
+		"nav-bar.ick.html":         "NavBar",
+		"./cmp/a_b_c.ick.html":     "ABC",
+		"./cmp/iconText.ick.html":  "IconText",
+		"./cmp/icon_text.ick.html": "IconText",
+	} {
+		if got := ComponentName(file); got != name {
+			t.Errorf("%s: %q expected, got %q", file, name, got)
+		}
+	}
+	if GeneratedFile("web/user_card.ick.html") != "web/user_card_ick.go" {
+		t.Errorf("unexpected generated file %q", GeneratedFile("web/user_card.ick.html"))
+	}
+}
@@ -0,0 +1,146 @@
+package ickc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LoadPackage parses and type-checks the go package in _dir, ignoring the previously generated files.
+// Components import syscall/js, so the package is loaded for the js/wasm target, its dependencies are
+// imported from the export data built by the go command.
+func LoadPackage(_dir string) (*types.Package, error) {
+	ctxt := build.Default
+	ctxt.GOOS, ctxt.GOARCH = "js", "wasm"
+	bp, err := ctxt.ImportDir(_dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	exports, path, err := listExports(_dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(bp.GoFiles))
+	for _, name := range bp.GoFiles {
+		if strings.HasSuffix(name, GENERATED_SUFFIX) {
+			continue
+		}
+		file, err := parser.ParseFile(fset, filepath.Join(_dir, name), nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+
+	errs := make([]error, 0)
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "gc", func(_path string) (io.ReadCloser, error) {
+			export, found := exports[_path]
+			if !found || export == "" {
+				return nil, fmt.Errorf("no export data for %q", _path)
+			}
+			return os.Open(export)
+		}),
+		Error: func(err error) {
+			if len(errs) < 10 {
+				errs = append(errs, err)
+			}
+		},
+	}
+	pkg, _ := conf.Check(path, fset, files, nil)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return pkg, nil
+}
+
+// listExports runs go list to build the dependencies of the _dir package for the js/wasm target,
+// and returns their export data files by import path, with the import path of the package.
+func listExports(_dir string) (_exports map[string]string, _path string, _err error) {
+	cmd := exec.Command("go", "list", "-e", "-export", "-deps", "-f", "{{.ImportPath}} {{.Export}}", ".")
+	cmd.Dir = _dir
+	cmd.Env = append(os.Environ(), "GOOS=js", "GOARCH=wasm")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, "", fmt.Errorf("go list failed: %w\n%s", err, stderr.String())
+	}
+	_exports = make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		path, export, _ := strings.Cut(line, " ")
+		_exports[path] = export
+		_path = path // the package itself is listed last
+	}
+	return _exports, _path, nil
+}
+
+// LookupComponent returns the component type named _name in the _pkg package, case insensitive
+func LookupComponent(_pkg *types.Package, _name string) (*types.Named, error) {
+	for _, name := range _pkg.Scope().Names() {
+		if !strings.EqualFold(name, _name) {
+			continue
+		}
+		if tn, ok := _pkg.Scope().Lookup(name).(*types.TypeName); ok {
+			if named, ok := tn.Type().(*types.Named); ok {
+				if _, ok := named.Underlying().(*types.Struct); ok {
+					return named, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("no component struct %s found in package %s", _name, _pkg.Name())
+}
+
+// Generate compiles every .ick.html template of the _dir package, and writes the generated go files.
+// Returns the names of the generated files, and the errors of every template.
+func Generate(_dir string) (_files []string, _err error) {
+	templates, err := filepath.Glob(filepath.Join(_dir, "*"+TEMPLATE_EXT))
+	if err != nil || len(templates) == 0 {
+		return nil, err
+	}
+	pkg, err := LoadPackage(_dir)
+	if err != nil {
+		return nil, err
+	}
+
+	errs := make([]error, 0)
+	for _, file := range templates {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		typ, err := LookupComponent(pkg, ComponentName(file))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s:1:1: %w", file, err))
+			continue
+		}
+		code, err := Compile(pkg, Template{File: file, Source: string(src), Type: typ})
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		target := GeneratedFile(file)
+		if err := os.WriteFile(target, code, 0644); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		_files = append(_files, target)
+	}
+	return _files, errors.Join(errs...)
+}
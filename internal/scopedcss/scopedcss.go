@@ -0,0 +1,199 @@
+// Package scopedcss rewrites the css of a component so it applies only to the elements rendered by the component.
+//
+// Every selector is restricted to the elements stamped with the scope attribute, by appending [attr] to its
+// last compound selector: `.title:hover` becomes `.title[data-ick-card]:hover`. The root element of the component,
+// stamped with the "host" value, is selected with :host or :host(.class), like with the shadow DOM:
+// `:host(.active) .title` becomes `[data-ick-card="host"].active .title[data-ick-card]`.
+//
+// Rules nested in @media, @supports, @container and @layer blocks are scoped, other at-rules like @keyframes
+// or @font-face are kept as is.
+package scopedcss
+
+import (
+	"fmt"
+	"strings"
+)
+
+// at-rules whose block contains style rules
+var nestingAtRules = map[string]bool{
+	"media": true, "supports": true, "container": true, "layer": true, "document": true,
+}
+
+// Scope returns the _css with every selector restricted to the elements with the _attr attribute.
+// Returns an error if the braces of the _css are not balanced.
+func Scope(_css string, _attr string) (string, error) {
+	var out strings.Builder
+	if err := scopeBlock(&out, _css, _attr); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// HostSelector returns the selector of the root element of a component scoped with the _attr attribute
+func HostSelector(_attr string) string {
+	return `[` + _attr + `="host"]`
+}
+
+// scopeBlock writes the rules of the _src block with their selectors scoped
+func scopeBlock(_out *strings.Builder, _src string, _attr string) error {
+	for len(_src) > 0 {
+		// copy spaces and comments as is
+		if skip := skipSpaces(_src); skip > 0 {
+			_out.WriteString(_src[:skip])
+			_src = _src[skip:]
+			continue
+		}
+
+		if _src[0] == '}' {
+			return fmt.Errorf("unexpected }")
+		}
+
+		// the prelude is the selector list, or the at-rule up to its block or its semicolon
+		end := scan(_src, 0, "{;}")
+		if end == len(_src) || _src[end] == '}' || (_src[end] == ';' && _src[0] != '@') {
+			return fmt.Errorf("missing { after %q", strings.TrimSpace(_src[:end]))
+		}
+		prelude := _src[:end]
+		if _src[end] == ';' {
+			_out.WriteString(_src[:end+1])
+			_src = _src[end+1:]
+			continue
+		}
+		close := scan(_src, end+1, "}")
+		if close == len(_src) {
+			return fmt.Errorf("missing } after %q", strings.TrimSpace(prelude))
+		}
+		body := _src[end+1 : close]
+		_src = _src[close+1:]
+
+		if prelude[0] == '@' {
+			name := strings.ToLower(strings.TrimLeft(strings.Fields(prelude)[0], "@"))
+			if !nestingAtRules[name] {
+				_out.WriteString(prelude + "{" + body + "}")
+				continue
+			}
+			_out.WriteString(prelude + "{")
+			if err := scopeBlock(_out, body, _attr); err != nil {
+				return err
+			}
+			_out.WriteString("}")
+			continue
+		}
+
+		selectors := split(prelude)
+		for i, selector := range selectors {
+			selectors[i] = scopeSelector(strings.TrimSpace(selector), _attr)
+		}
+		_out.WriteString(strings.Join(selectors, ", ") + " {" + body + "}")
+	}
+	return nil
+}
+
+// scopeSelector restricts a single _selector to the elements with the _attr attribute
+func scopeSelector(_selector string, _attr string) string {
+	host := HostSelector(_attr)
+
+	// replace :host and :host(...) with the host attribute
+	var sel strings.Builder
+	for i := 0; i < len(_selector); {
+		if !strings.HasPrefix(_selector[i:], ":host") || (i+5 < len(_selector) && isNameChar(_selector[i+5])) {
+			end := scan(_selector, i+1, ":")
+			sel.WriteString(_selector[i:end])
+			i = end
+			continue
+		}
+		sel.WriteString(host)
+		i += 5
+		if i < len(_selector) && _selector[i] == '(' {
+			close := scan(_selector, i+1, ")")
+			sel.WriteString(_selector[i+1 : close])
+			i = close + 1
+		}
+	}
+	selector := sel.String()
+
+	// append the attribute to the last compound, before its pseudo classes and elements
+	last := 0
+	for i := 0; i < len(selector); {
+		end := scan(selector, i, " \t\n\r\f>+~")
+		if end < len(selector) {
+			last = end + 1
+		}
+		i = end + 1
+	}
+	compound := selector[last:]
+	if strings.Contains(compound, host) {
+		return selector
+	}
+	pseudo := scan(compound, 0, ":")
+	return selector[:last] + compound[:pseudo] + "[" + _attr + "]" + compound[pseudo:]
+}
+
+// split splits the _list of selectors on its top level commas
+func split(_list string) []string {
+	parts := make([]string, 0, 1)
+	for {
+		end := scan(_list, 0, ",")
+		parts = append(parts, _list[:end])
+		if end == len(_list) {
+			return parts
+		}
+		_list = _list[end+1:]
+	}
+}
+
+// scan returns the index of the first char of _stops in _src from _from, outside strings, comments,
+// parentheses, brackets and nested braces, or len(_src) if not found. Escaped chars are skipped.
+func scan(_src string, _from int, _stops string) int {
+	depth := 0
+	for i := _from; i < len(_src); i++ {
+		c := _src[i]
+		switch {
+		case c == '\\':
+			i++
+		case c == '"' || c == '\'':
+			for i++; i < len(_src) && _src[i] != c; i++ {
+				if _src[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(_src) && _src[i+1] == '*':
+			if end := strings.Index(_src[i+2:], "*/"); end != -1 {
+				i += end + 3
+			} else {
+				i = len(_src)
+			}
+		case depth == 0 && strings.IndexByte(_stops, c) != -1:
+			return i
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		}
+	}
+	return len(_src)
+}
+
+// skipSpaces returns the length of the spaces and comments at the beginning of _src
+func skipSpaces(_src string) int {
+	i := 0
+	for i < len(_src) {
+		switch {
+		case strings.IndexByte(" \t\n\r\f", _src[i]) != -1:
+			i++
+		case strings.HasPrefix(_src[i:], "/*"):
+			end := strings.Index(_src[i+2:], "*/")
+			if end == -1 {
+				return len(_src)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+func isNameChar(_c byte) bool {
+	return _c == '-' || _c == '_' || (_c >= 'a' && _c <= 'z') || (_c >= 'A' && _c <= 'Z') || (_c >= '0' && _c <= '9')
+}
@@ -0,0 +1,39 @@
+package scopedcss
+
+import "testing"
+
+func TestScope(t *testing.T) {
+	const attr = "data-ick-card"
+	tests := []struct {
+		css      string
+		expected string
+	}{
+		{css: `.title { color: red; }`, expected: `.title[data-ick-card] { color: red; }`},
+		{css: `.a .b:hover, ul > li::before {x:y}`, expected: `.a .b[data-ick-card]:hover, ul > li[data-ick-card]::before {x:y}`},
+		{css: `:host { opacity: 0 }`, expected: `[data-ick-card="host"] { opacity: 0 }`},
+		{css: `:host(.show.toast) .title, :host-context(.x) {}`, expected: `[data-ick-card="host"].show.toast .title[data-ick-card], [data-ick-card]:host-context(.x) {}`},
+		{css: `input[type="a, b"]:not(:checked) + *{}`, expected: `input[type="a, b"]:not(:checked) + *[data-ick-card] {}`},
+		{css: `.md\:flex{}`, expected: `.md\:flex[data-ick-card] {}`},
+		{
+			css:      "/* c { } */\n@import url(\"x.css\");\n@media (min-width: 10px) {\n  .a { b: c }\n}\n@keyframes fade { from { opacity: 0 } }",
+			expected: "/* c { } */\n@import url(\"x.css\");\n@media (min-width: 10px) {\n  .a[data-ick-card] { b: c }\n}\n@keyframes fade { from { opacity: 0 } }",
+		},
+		{css: `.a { content: "}" }`, expected: `.a[data-ick-card] { content: "}" }`},
+	}
+	for i, tst := range tests {
+		got, err := Scope(tst.css, attr)
+		if err != nil {
+			t.Errorf("test %d: %s", i, err)
+			continue
+		}
+		if got != tst.expected {
+			t.Errorf("test %d:\n%q expected, got\n%q", i, tst.expected, got)
+		}
+	}
+
+	for _, css := range []string{`.a { b: c`, `.a } .b {}`, `.a; .b {}`} {
+		if _, err := Scope(css, attr); err == nil {
+			t.Errorf("%q: error expected", css)
+		}
+	}
+}
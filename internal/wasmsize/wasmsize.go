@@ -0,0 +1,380 @@
+// Package wasmsize parses wasm binaries built by the go toolchain, and reports the code size per go package and per function.
+package wasmsize
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// wasm section ids
+const (
+	section_custom = 0
+	section_import = 2
+	section_code   = 10
+	section_data   = 11
+)
+
+// Symbol is the code size of a function
+type Symbol struct {
+	Name    string `json:"name"`
+	Package string `json:"package"`
+	Size    int    `json:"size"`
+}
+
+// PackageSize is the code size of a go package, the sum of its functions size
+type PackageSize struct {
+	Package string `json:"package"`
+	Size    int    `json:"size"`
+	Delta   int    `json:"delta,omitempty"` // the size difference with the previous report, set by Diff
+}
+
+// Report is the size analysis of a wasm file
+type Report struct {
+	File      string        `json:"file"`
+	Size      int           `json:"size"`      // the file size
+	CodeSize  int           `json:"codeSize"`  // the size of the code section
+	DataSize  int           `json:"dataSize"`  // the size of the data section
+	Packages  []PackageSize `json:"packages"`  // sorted by decreasing size
+	Functions []Symbol      `json:"functions"` // sorted by decreasing size
+	Delta     int           `json:"delta"`     // the file size difference with the previous report, set by Diff
+}
+
+// Analyze parses the _filename wasm file and returns its size report
+func Analyze(_filename string) (*Report, error) {
+	data, err := os.ReadFile(_filename)
+	if err != nil {
+		return nil, err
+	}
+	rep, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", _filename, err)
+	}
+	rep.File = _filename
+	return rep, nil
+}
+
+// Load returns the report of the _filename file, either a json report or a wasm file
+func Load(_filename string) (*Report, error) {
+	if !strings.HasSuffix(_filename, ".json") {
+		return Analyze(_filename)
+	}
+	data, err := os.ReadFile(_filename)
+	if err != nil {
+		return nil, err
+	}
+	rep := new(Report)
+	if err := json.Unmarshal(data, rep); err != nil {
+		return nil, fmt.Errorf("%s: %w", _filename, err)
+	}
+	return rep, nil
+}
+
+// Parse parses the _data wasm binary and returns its size report.
+// Function names come from the name custom section, functions are named by their index if it's missing.
+func Parse(_data []byte) (*Report, error) {
+	if len(_data) < 8 || !bytes.Equal(_data[:4], []byte("\x00asm")) {
+		return nil, errors.New("not a wasm binary")
+	}
+	rep := &Report{Size: len(_data)}
+
+	nimports := 0
+	bodies := make([]int, 0)
+	names := make(map[int]string)
+
+	r := &reader{data: _data, pos: 8}
+	for r.pos < len(r.data) {
+		id := r.byte()
+		size := int(r.uleb())
+		if r.err != nil || r.pos+size > len(r.data) {
+			return nil, errors.New("truncated section")
+		}
+		sec := &reader{data: r.data[r.pos : r.pos+size]}
+		r.pos += size
+
+		switch id {
+		case section_import:
+			nimports = sec.funcImports()
+		case section_code:
+			rep.CodeSize = size
+			count := int(sec.uleb())
+			for i := 0; i < count && sec.err == nil; i++ {
+				start := sec.pos
+				bodysize := int(sec.uleb())
+				sec.pos += bodysize
+				bodies = append(bodies, sec.pos-start)
+			}
+		case section_data:
+			rep.DataSize = size
+		case section_custom:
+			if sec.name() == "name" {
+				sec.funcNames(names)
+			}
+		}
+		if sec.err != nil {
+			return nil, fmt.Errorf("section %d: %w", id, sec.err)
+		}
+	}
+
+	pkgs := make(map[string]int)
+	rep.Functions = make([]Symbol, 0, len(bodies))
+	for i, size := range bodies {
+		name, found := names[nimports+i]
+		if !found {
+			name = fmt.Sprintf("func[%d]", nimports+i)
+		}
+		sym := Symbol{Name: name, Package: PackageOf(name), Size: size}
+		rep.Functions = append(rep.Functions, sym)
+		pkgs[sym.Package] += size
+	}
+	sort.SliceStable(rep.Functions, func(i, j int) bool { return rep.Functions[i].Size > rep.Functions[j].Size })
+
+	rep.Packages = make([]PackageSize, 0, len(pkgs))
+	for pkg, size := range pkgs {
+		rep.Packages = append(rep.Packages, PackageSize{Package: pkg, Size: size})
+	}
+	sortPackages(rep.Packages)
+	return rep, nil
+}
+
+// rexpMangledDomain matches the domain name starting a mangled package path.
+// The top-level domains are listed to not confuse "runtime.park_m" with a domain.
+var rexpMangledDomain = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)*\.(com|org|net|io|dev|in|co|me|app|cloud|sh|xyz|info|eu|fr|de|uk|tech)_`)
+
+// PackageOf returns the go package of the _symbol, ie. "github.com/sunraylab/icecake/pkg/icecake" for
+// "github.com/sunraylab/icecake/pkg/icecake.(*Element).SetId". Returns an empty string for non go symbols.
+//
+// The go linker mangles wasm names, replacing "/" by "_", so "_" are turned back into "/" within mangled package paths.
+// Packages with an underscore in their name are reported with a "/" instead.
+func PackageOf(_symbol string) string {
+	// generated type functions, ie. "type:.eq.main.T"
+	if strings.HasPrefix(_symbol, "type:") || strings.HasPrefix(_symbol, "type_.") {
+		return "(types)"
+	}
+	if _symbol == "" || _symbol[0] == '_' {
+		return ""
+	}
+	start := 0
+	if slash := strings.LastIndex(_symbol, "/"); slash >= 0 {
+		start = slash + 1
+	} else {
+		// the packages vendored into the std library are prefixed with "vendor_"
+		vendor := 0
+		if strings.HasPrefix(_symbol, "vendor_") {
+			vendor = len("vendor_")
+		}
+		if loc := rexpMangledDomain.FindStringIndex(_symbol[vendor:]); loc != nil {
+			// mangled path starting with a domain name, ie. "github.com_yuin_goldmark.New"
+			start = vendor + loc[1]
+		}
+	}
+	dot := strings.Index(_symbol[start:], ".")
+	if dot <= 0 {
+		return ""
+	}
+	return strings.ReplaceAll(_symbol[:start+dot], "_", "/")
+}
+
+// Diff sets the size differences of _rep with the _previous report, per package and for the whole file.
+// Packages removed since the previous report are added with a zero size.
+func (_rep *Report) Diff(_previous *Report) {
+	_rep.Delta = _rep.Size - _previous.Size
+	prev := make(map[string]int, len(_previous.Packages))
+	for _, p := range _previous.Packages {
+		prev[p.Package] = p.Size
+	}
+	for i, p := range _rep.Packages {
+		_rep.Packages[i].Delta = p.Size - prev[p.Package]
+		delete(prev, p.Package)
+	}
+	for pkg, size := range prev {
+		_rep.Packages = append(_rep.Packages, PackageSize{Package: pkg, Delta: -size})
+	}
+	sortPackages(_rep.Packages)
+}
+
+func sortPackages(_pkgs []PackageSize) {
+	sort.Slice(_pkgs, func(i, j int) bool {
+		if _pkgs[i].Size != _pkgs[j].Size {
+			return _pkgs[i].Size > _pkgs[j].Size
+		}
+		return _pkgs[i].Package < _pkgs[j].Package
+	})
+}
+
+// WriteTable writes the report as text tables, with the _top biggest functions
+func (_rep *Report) WriteTable(w io.Writer, _top int, _withdelta bool) {
+	fmt.Fprintf(w, "%s: %d bytes", _rep.File, _rep.Size)
+	if _withdelta {
+		fmt.Fprintf(w, " (%+d)", _rep.Delta)
+	}
+	fmt.Fprintf(w, ", code %d bytes, data %d bytes\n\n", _rep.CodeSize, _rep.DataSize)
+
+	fmt.Fprintf(w, "%10s %7s", "SIZE", "%CODE")
+	if _withdelta {
+		fmt.Fprintf(w, " %10s", "DELTA")
+	}
+	fmt.Fprintln(w, "  PACKAGE")
+	for _, p := range _rep.Packages {
+		fmt.Fprintf(w, "%10d %6.1f%%", p.Size, percent(p.Size, _rep.CodeSize))
+		if _withdelta {
+			fmt.Fprintf(w, " %+10d", p.Delta)
+		}
+		fmt.Fprintf(w, "  %s\n", packageLabel(p.Package))
+	}
+
+	if _top > len(_rep.Functions) {
+		_top = len(_rep.Functions)
+	}
+	fmt.Fprintf(w, "\n%10s %7s  FUNCTION\n", "SIZE", "%CODE")
+	for _, f := range _rep.Functions[:_top] {
+		fmt.Fprintf(w, "%10d %6.1f%%  %s\n", f.Size, percent(f.Size, _rep.CodeSize), f.Name)
+	}
+}
+
+// WriteJSON writes the report in json, with the _top biggest functions only
+func (_rep *Report) WriteJSON(w io.Writer, _top int) error {
+	out := *_rep
+	if _top < len(out.Functions) {
+		out.Functions = out.Functions[:_top]
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
+
+func packageLabel(_pkg string) string {
+	if _pkg == "" {
+		return "(other)"
+	}
+	return _pkg
+}
+
+func percent(_size int, _total int) float64 {
+	if _total == 0 {
+		return 0
+	}
+	return float64(_size) * 100 / float64(_total)
+}
+
+/******************************************************************************
+* binary reader
+******************************************************************************/
+
+var errTruncated = errors.New("unexpected end of data")
+
+// reader reads wasm binary values, the first error stops the reading
+type reader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (r *reader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.data) {
+		r.err = errTruncated
+		return 0
+	}
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+// uleb reads an unsigned LEB128 integer
+func (r *reader) uleb() uint64 {
+	var v uint64
+	for shift := uint(0); shift < 64; shift += 7 {
+		b := r.byte()
+		if r.err != nil {
+			return 0
+		}
+		v |= uint64(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return v
+		}
+	}
+	r.err = errors.New("invalid leb128 integer")
+	return 0
+}
+
+func (r *reader) name() string {
+	n := int(r.uleb())
+	if r.err != nil {
+		return ""
+	}
+	if r.pos+n > len(r.data) {
+		r.err = errTruncated
+		return ""
+	}
+	s := string(r.data[r.pos : r.pos+n])
+	r.pos += n
+	return s
+}
+
+func (r *reader) limits() {
+	if flags := r.byte(); flags&1 != 0 {
+		r.uleb()
+		r.uleb()
+	} else {
+		r.uleb()
+	}
+}
+
+// funcImports reads the import section and returns the number of imported functions,
+// which come first in the function index space
+func (r *reader) funcImports() (_n int) {
+	count := int(r.uleb())
+	for i := 0; i < count && r.err == nil; i++ {
+		r.name() // module
+		r.name() // field
+		switch kind := r.byte(); kind {
+		case 0: // function
+			r.uleb()
+			_n++
+		case 1: // table
+			r.byte()
+			r.limits()
+		case 2: // memory
+			r.limits()
+		case 3: // global
+			r.byte()
+			r.byte()
+		default:
+			r.err = fmt.Errorf("unknown import kind %d", kind)
+		}
+	}
+	return _n
+}
+
+// funcNames reads the function names subsection of the name section
+func (r *reader) funcNames(_names map[int]string) {
+	for r.pos < len(r.data) && r.err == nil {
+		id := r.byte()
+		size := int(r.uleb())
+		if r.err != nil || r.pos+size > len(r.data) {
+			r.err = errTruncated
+			return
+		}
+		if id != 1 {
+			r.pos += size
+			continue
+		}
+		sub := &reader{data: r.data[r.pos : r.pos+size]}
+		r.pos += size
+		count := int(sub.uleb())
+		for i := 0; i < count && sub.err == nil; i++ {
+			idx := int(sub.uleb())
+			_names[idx] = sub.name()
+		}
+		r.err = sub.err
+	}
+}
@@ -0,0 +1,102 @@
+package wasmsize
+
+import (
+	"bytes"
+	"testing"
+)
+
+// section returns a wasm section with its id and size
+func section(_id byte, _content ...byte) []byte {
+	return append([]byte{_id, byte(len(_content))}, _content...)
+}
+
+// str returns a wasm name, its length followed by its bytes
+func str(_s string) []byte {
+	return append([]byte{byte(len(_s))}, _s...)
+}
+
+func concat(_parts ...[]byte) []byte {
+	return bytes.Join(_parts, nil)
+}
+
+// testWasm returns a tiny wasm binary with one imported function and two functions named by the name section
+func testWasm() []byte {
+	imports := section(section_import, concat([]byte{1}, str("go"), str("debug"), []byte{0, 0})...)
+	code := section(section_code, concat([]byte{2},
+		[]byte{2, 0, 0x0b},       // main.small, 3 bytes
+		[]byte{4, 0, 1, 1, 0x0b}, // fmt.Println, 5 bytes
+	)...)
+	funcnames := concat([]byte{3},
+		[]byte{0}, str("go.debug"),
+		[]byte{1}, str("main.small"),
+		[]byte{2}, str("fmt.Println"),
+	)
+	names := section(section_custom, concat(str("name"), []byte{1, byte(len(funcnames))}, funcnames)...)
+	return concat([]byte("\x00asm\x01\x00\x00\x00"), imports, code, names)
+}
+
+func TestParse(t *testing.T) {
+	data := testWasm()
+	rep, err := Parse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Size != len(data) || rep.CodeSize != 9 {
+		t.Errorf("wrong sizes %d %d", rep.Size, rep.CodeSize)
+	}
+	if len(rep.Functions) != 2 || rep.Functions[0] != (Symbol{Name: "fmt.Println", Package: "fmt", Size: 5}) || rep.Functions[1] != (Symbol{Name: "main.small", Package: "main", Size: 3}) {
+		t.Errorf("unexpected functions %+v", rep.Functions)
+	}
+	if len(rep.Packages) != 2 || rep.Packages[0].Package != "fmt" || rep.Packages[1].Package != "main" {
+		t.Errorf("unexpected packages %+v", rep.Packages)
+	}
+
+	if _, err := Parse([]byte("not wasm")); err == nil {
+		t.Errorf("invalid binary must fail")
+	}
+	if _, err := Parse(data[:len(data)-3]); err == nil {
+		t.Errorf("truncated binary must fail")
+	}
+}
+
+func TestPackageOf(t *testing.T) {
+	tests := []struct {
+		symbol string
+		pkg    string
+	}{
+		{"fmt.Println", "fmt"},
+		{"runtime.park_m", "runtime"},
+		{"runtime_debug.Stack", "runtime/debug"},
+		{"encoding/json.(*Decoder).Decode", "encoding/json"},
+		{"github.com/sunraylab/icecake/pkg/icecake.(*Element).SetId", "github.com/sunraylab/icecake/pkg/icecake"},
+		{"github.com_yuin_goldmark_util.map.init.0", "github.com/yuin/goldmark/util"},
+		{"vendor_golang.org_x_net_dns_dnsmessage.(*Parser).Start", "vendor/golang.org/x/net/dns/dnsmessage"},
+		{"vendor/golang.org/x/crypto/chacha20.(*Cipher).XORKeyStream", "vendor/golang.org/x/crypto/chacha20"},
+		{"type:.eq.main.T", "(types)"},
+		{"wasm_export_run", ""},
+		{"_rt0_wasm_js", ""},
+	}
+	for _, tst := range tests {
+		if pkg := PackageOf(tst.symbol); pkg != tst.pkg {
+			t.Errorf("%s: package %q expected, got %q", tst.symbol, tst.pkg, pkg)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	prev := &Report{Size: 100, Packages: []PackageSize{{Package: "fmt", Size: 50}, {Package: "os", Size: 20}}}
+	rep := &Report{Size: 130, Packages: []PackageSize{{Package: "fmt", Size: 60}, {Package: "main", Size: 40}}}
+	rep.Diff(prev)
+	if rep.Delta != 30 {
+		t.Errorf("wrong delta %d", rep.Delta)
+	}
+	expected := []PackageSize{{Package: "fmt", Size: 60, Delta: 10}, {Package: "main", Size: 40, Delta: 40}, {Package: "os", Delta: -20}}
+	if len(rep.Packages) != len(expected) {
+		t.Fatalf("unexpected packages %+v", rep.Packages)
+	}
+	for i := range expected {
+		if rep.Packages[i] != expected[i] {
+			t.Errorf("package %d: %+v expected, got %+v", i, expected[i], rep.Packages[i])
+		}
+	}
+}
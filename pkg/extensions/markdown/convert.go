@@ -0,0 +1,33 @@
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/sunraylab/icecake/pkg/sanitize"
+	"github.com/yuin/goldmark"
+)
+
+// ConvertMarkdown converts _mdtxt markdown source to an HTML string, without processing it as a template.
+//
+// ConvertMarkdown does not depend on the DOM, so it's used both by the wasm app and by the static site export.
+func ConvertMarkdown(_mdtxt string, _options ...goldmark.Option) (string, error) {
+	md := goldmark.New(_options...)
+	var buf bytes.Buffer
+	if err := md.Convert([]byte(_mdtxt), &buf); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ConvertSafeMarkdown converts untrusted _mdtxt markdown source to an HTML string sanitized with the _policy,
+// or with the sanitize.DefaultPolicy if _policy is nil.
+func ConvertSafeMarkdown(_mdtxt string, _policy *sanitize.Policy, _options ...goldmark.Option) (string, error) {
+	html, err := ConvertMarkdown(_mdtxt, _options...)
+	if err != nil {
+		return "", err
+	}
+	if _policy == nil {
+		_policy = sanitize.DefaultPolicy()
+	}
+	return _policy.Sanitize(html), nil
+}
@@ -1,30 +1,50 @@
+//go:build js && wasm
+
 package markdown
 
 import (
-	"bytes"
-
 	"github.com/sunraylab/icecake/pkg/errors"
 	ick "github.com/sunraylab/icecake/pkg/icecake"
+	"github.com/sunraylab/icecake/pkg/sanitize"
 	"github.com/yuin/goldmark"
 )
 
 // RenderMarkdown process _mdtxt markdown source file and convert it to an HTML string,
 // then use it as an HTML template to render it with data and components.
+// The markdown source must be trusted, use RenderSafeMarkdown otherwise.
+//
+// The content of an element prerendered by the static site export is kept, its components are only hydrated.
 //
 // Returns an error if the markdown processor fails.
 func RenderMarkdown(_elem *ick.Element, _mdtxt string, _data any, _options ...goldmark.Option) error {
-	if !_elem.IsDefined() {
+	if !_elem.IsDefined() || ick.App.Hydrate(_elem) {
 		return nil
 	}
-	md := goldmark.New(_options...)
-	var buf bytes.Buffer
-	err := md.Convert([]byte(_mdtxt), &buf)
+	html, err := ConvertMarkdown(_mdtxt, _options...)
 	if err != nil {
 		errors.ConsoleWarnf("RenderMarkdown has error: %s", err.Error())
 		return err
 	}
 
 	// HACK:
-	_elem.RenderTemplate(buf.String(), _data)
+	_elem.RenderTemplate(html, _data)
+	return nil
+}
+
+// RenderSafeMarkdown converts the untrusted _mdtxt markdown source to an HTML string sanitized with the _policy,
+// or with the sanitize.DefaultPolicy if _policy is nil, then sets it as the inner html of _elem.
+// Unlike RenderMarkdown the HTML is not used as a template, so it never renders data nor components.
+//
+// Returns an error if the markdown processor fails.
+func RenderSafeMarkdown(_elem *ick.Element, _mdtxt string, _policy *sanitize.Policy, _options ...goldmark.Option) error {
+	if !_elem.IsDefined() {
+		return nil
+	}
+	html, err := ConvertSafeMarkdown(_mdtxt, _policy, _options...)
+	if err != nil {
+		errors.ConsoleWarnf("RenderSafeMarkdown has error: %s", err.Error())
+		return err
+	}
+	_elem.SetInnerHTML(html)
 	return nil
 }
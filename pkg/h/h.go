@@ -0,0 +1,274 @@
+// Package h is a typed builder of html node trees, an alternative to html string templates:
+//
+//	card := h.Div(h.Class("box"), h.Attr("role", "alert"),
+//		h.P(h.Text(msg)),
+//		h.Button(h.Class("delete"), h.OnClick(onclose)),
+//	)
+//
+// A node tree is mounted into an ick.Element by the wasm app with Mount, which attaches the event listeners,
+// or rendered to an html string with HTML, by the wasm app and by the server-side renderer.
+//
+// Texts and attribute values are escaped, only UnsafeHTML inserts html as is.
+package h
+
+import (
+	"html"
+	"io"
+	"strings"
+)
+
+/******************************************************************************
+* Node
+******************************************************************************/
+
+// Item is an argument of the element builders: a child Node, an attribute, a class or an event listener.
+type Item interface {
+	applyTo(_elem *ElementNode)
+}
+
+// Node is a node of the tree: an *ElementNode, a text or unsafe html.
+type Node interface {
+	Item
+	writeHTML(_out *strings.Builder)
+}
+
+// HTML returns the html string of the _nodes
+func HTML(_nodes ...Node) string {
+	var out strings.Builder
+	for _, node := range _nodes {
+		node.writeHTML(&out)
+	}
+	return out.String()
+}
+
+// Render writes the html of the _nodes into _w
+func Render(_w io.Writer, _nodes ...Node) error {
+	_, err := io.WriteString(_w, HTML(_nodes...))
+	return err
+}
+
+// TextNode is a text, escaped when rendered
+type TextNode string
+
+// Text returns a text node, escaped when rendered
+func Text(_text string) TextNode {
+	return TextNode(_text)
+}
+
+func (_text TextNode) applyTo(_elem *ElementNode) {
+	_elem.Children = append(_elem.Children, _text)
+}
+
+func (_text TextNode) writeHTML(_out *strings.Builder) {
+	_out.WriteString(html.EscapeString(string(_text)))
+}
+
+// HTMLNode is an html string inserted as is
+type HTMLNode string
+
+// UnsafeHTML returns an html node inserted as is, without any escaping.
+// Never use it with user inputs.
+func UnsafeHTML(_unsafeHtml string) HTMLNode {
+	return HTMLNode(_unsafeHtml)
+}
+
+func (_html HTMLNode) applyTo(_elem *ElementNode) {
+	_elem.Children = append(_elem.Children, _html)
+}
+
+func (_html HTMLNode) writeHTML(_out *strings.Builder) {
+	_out.WriteString(string(_html))
+}
+
+/******************************************************************************
+* ElementNode
+******************************************************************************/
+
+// void elements never have children nor a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// ElementNode is an html element with its attributes, classes, children and event listeners.
+type ElementNode struct {
+	Tag      string
+	Attrs    []Attribute // attributes in the order they were given, the class attribute excepted
+	Classes  []string
+	Children []Node
+
+	listeners []any // the func(*ick.Element) attaching the event listeners, set by the wasm event builders and called by Mount
+}
+
+// Attribute is an html attribute
+type Attribute struct {
+	Name  string
+	Value string
+}
+
+// El returns a new _tag element built with the _items
+func El(_tag string, _items ...Item) *ElementNode {
+	elem := &ElementNode{Tag: strings.ToLower(_tag)}
+	return elem.With(_items...)
+}
+
+// Attr returns the value of the _name attribute, and whether it's set
+func (_elem *ElementNode) Attr(_name string) (_value string, _found bool) {
+	for _, attr := range _elem.Attrs {
+		if attr.Name == _name {
+			return attr.Value, true
+		}
+	}
+	return "", false
+}
+
+// With applies the _items to the element already built, and returns it
+func (_elem *ElementNode) With(_items ...Item) *ElementNode {
+	for _, item := range _items {
+		if item != nil {
+			item.applyTo(_elem)
+		}
+	}
+	return _elem
+}
+
+func (_elem *ElementNode) applyTo(_parent *ElementNode) {
+	_parent.Children = append(_parent.Children, _elem)
+}
+
+func (_elem *ElementNode) writeHTML(_out *strings.Builder) {
+	_out.WriteString("<" + _elem.Tag)
+	if len(_elem.Classes) > 0 {
+		_out.WriteString(` class="` + html.EscapeString(strings.Join(_elem.Classes, " ")) + `"`)
+	}
+	for _, attr := range _elem.Attrs {
+		_out.WriteString(" " + attr.Name + `="` + html.EscapeString(attr.Value) + `"`)
+	}
+	_out.WriteString(">")
+	if voidElements[_elem.Tag] {
+		return
+	}
+	for _, child := range _elem.Children {
+		child.writeHTML(_out)
+	}
+	_out.WriteString("</" + _elem.Tag + ">")
+}
+
+/******************************************************************************
+* Attributes
+******************************************************************************/
+
+type itemFunc func(_elem *ElementNode)
+
+func (_fn itemFunc) applyTo(_elem *ElementNode) {
+	_fn(_elem)
+}
+
+// Attr sets the _name attribute, overwriting a previous value. Use Class to add classes.
+func Attr(_name string, _value string) Item {
+	return itemFunc(func(_elem *ElementNode) {
+		_name = strings.ToLower(strings.Trim(_name, " "))
+		if _name == "class" {
+			Class(_value).applyTo(_elem)
+			return
+		}
+		for i, attr := range _elem.Attrs {
+			if attr.Name == _name {
+				_elem.Attrs[i].Value = _value
+				return
+			}
+		}
+		_elem.Attrs = append(_elem.Attrs, Attribute{Name: _name, Value: _value})
+	})
+}
+
+// Id sets the id attribute
+func Id(_id string) Item {
+	return Attr("id", _id)
+}
+
+// Style sets the inline style attribute
+func Style(_css string) Item {
+	return Attr("style", _css)
+}
+
+// Data sets the data-_name attribute
+func Data(_name string, _value string) Item {
+	return Attr("data-"+_name, _value)
+}
+
+// Class adds the classes, each _classes string can hold several space separated classes.
+// Duplicated classes are ignored.
+func Class(_classes ...string) Item {
+	return itemFunc(func(_elem *ElementNode) {
+		for _, classes := range _classes {
+		next:
+			for _, class := range strings.Fields(classes) {
+				for _, existing := range _elem.Classes {
+					if existing == class {
+						continue next
+					}
+				}
+				_elem.Classes = append(_elem.Classes, class)
+			}
+		}
+	})
+}
+
+// Group returns a single item applying all the _items, to build several items with a function
+func Group(_items ...Item) Item {
+	return itemFunc(func(_elem *ElementNode) {
+		for _, item := range _items {
+			if item != nil {
+				item.applyTo(_elem)
+			}
+		}
+	})
+}
+
+// If returns the _items only if _condition is true
+func If(_condition bool, _items ...Item) Item {
+	if !_condition {
+		return nil
+	}
+	return Group(_items...)
+}
+
+/******************************************************************************
+* Elements
+******************************************************************************/
+
+func A(_items ...Item) *ElementNode        { return El("a", _items...) }
+func Article(_items ...Item) *ElementNode  { return El("article", _items...) }
+func Br(_items ...Item) *ElementNode       { return El("br", _items...) }
+func Button(_items ...Item) *ElementNode   { return El("button", _items...) }
+func Code(_items ...Item) *ElementNode     { return El("code", _items...) }
+func Div(_items ...Item) *ElementNode      { return El("div", _items...) }
+func Em(_items ...Item) *ElementNode       { return El("em", _items...) }
+func Footer(_items ...Item) *ElementNode   { return El("footer", _items...) }
+func Form(_items ...Item) *ElementNode     { return El("form", _items...) }
+func H1(_items ...Item) *ElementNode       { return El("h1", _items...) }
+func H2(_items ...Item) *ElementNode       { return El("h2", _items...) }
+func H3(_items ...Item) *ElementNode       { return El("h3", _items...) }
+func Header(_items ...Item) *ElementNode   { return El("header", _items...) }
+func Hr(_items ...Item) *ElementNode       { return El("hr", _items...) }
+func I(_items ...Item) *ElementNode        { return El("i", _items...) }
+func Img(_items ...Item) *ElementNode      { return El("img", _items...) }
+func Input(_items ...Item) *ElementNode    { return El("input", _items...) }
+func Label(_items ...Item) *ElementNode    { return El("label", _items...) }
+func Li(_items ...Item) *ElementNode       { return El("li", _items...) }
+func Main(_items ...Item) *ElementNode     { return El("main", _items...) }
+func Nav(_items ...Item) *ElementNode      { return El("nav", _items...) }
+func Ol(_items ...Item) *ElementNode       { return El("ol", _items...) }
+func P(_items ...Item) *ElementNode        { return El("p", _items...) }
+func Pre(_items ...Item) *ElementNode      { return El("pre", _items...) }
+func Section(_items ...Item) *ElementNode  { return El("section", _items...) }
+func Select(_items ...Item) *ElementNode   { return El("select", _items...) }
+func Span(_items ...Item) *ElementNode     { return El("span", _items...) }
+func Strong(_items ...Item) *ElementNode   { return El("strong", _items...) }
+func Table(_items ...Item) *ElementNode    { return El("table", _items...) }
+func Td(_items ...Item) *ElementNode       { return El("td", _items...) }
+func Textarea(_items ...Item) *ElementNode { return El("textarea", _items...) }
+func Th(_items ...Item) *ElementNode       { return El("th", _items...) }
+func Tr(_items ...Item) *ElementNode       { return El("tr", _items...) }
+func Ul(_items ...Item) *ElementNode       { return El("ul", _items...) }
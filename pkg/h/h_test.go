@@ -0,0 +1,49 @@
+package h
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	msg := `<script>alert("x")</script>`
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{node: Text(msg), expected: `&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`},
+		{node: UnsafeHTML("<b>bold</b>"), expected: `<b>bold</b>`},
+		{
+			node:     Div(Class("box"), Attr("role", "alert"), Text("hello"), Span(Text("!"))),
+			expected: `<div class="box" role="alert">hello<span>!</span></div>`,
+		},
+		{
+			node:     Div(Class("a b"), Class("b", "c"), Attr("class", "d"), Id("x"), Attr("id", "y"), Data("key", `"q"`)),
+			expected: `<div class="a b c d" id="y" data-key="&#34;q&#34;"></div>`,
+		},
+		{
+			node:     Ul(Li(Text("one")), If(false, Li(Text("two"))), If(true, Class("on"), Li(Text("three"))), nil),
+			expected: `<ul class="on"><li>one</li><li>three</li></ul>`,
+		},
+		{node: P(Text("a"), Br(), Img(Attr("src", "x.png")), Input(Attr("disabled", ""))), expected: `<p>a<br><img src="x.png"><input disabled=""></p>`},
+		{node: El("MY-TAG", Group(Style("color: red"), Text("x"))), expected: `<my-tag style="color: red">x</my-tag>`},
+	}
+	for i, tst := range tests {
+		if got := HTML(tst.node); got != tst.expected {
+			t.Errorf("test %d: %q expected, got %q", i, tst.expected, got)
+		}
+	}
+
+	var out strings.Builder
+	if err := Render(&out, Text("a"), Strong(Text("b"))); err != nil || out.String() != "a<strong>b</strong>" {
+		t.Errorf("unexpected render %q, %v", out.String(), err)
+	}
+
+	div := Div(Attr("role", "alert"))
+	if role, found := div.Attr("role"); !found || role != "alert" {
+		t.Errorf("role attribute expected")
+	}
+	if got := HTML(div.With(Attr("role", "status"), Class("box"), nil)); got != `<div class="box" role="status"></div>` {
+		t.Errorf("unexpected element %q", got)
+	}
+}
@@ -0,0 +1,101 @@
+//go:build js && wasm
+
+package h
+
+import (
+	"strings"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+	ick "github.com/sunraylab/icecake/pkg/icecake"
+)
+
+/******************************************************************************
+* Events
+******************************************************************************/
+
+// on returns an item adding the _attach function, called by Mount with the DOM element
+func on(_attach func(*ick.Element)) Item {
+	return itemFunc(func(_elem *ElementNode) {
+		_elem.listeners = append(_elem.listeners, _attach)
+	})
+}
+
+// OnClick adds a click event listener, attached during the mount
+func OnClick(_listener func(*ick.MouseEvent, *ick.Element)) Item {
+	return OnMouse(ick.MOUSE_ONCLICK, _listener)
+}
+
+// OnMouse adds a mouse event listener, attached during the mount
+func OnMouse(_evttype ick.MOUSE_EVENT, _listener func(*ick.MouseEvent, *ick.Element)) Item {
+	return on(func(_elem *ick.Element) { _elem.AddMouseEvent(_evttype, _listener) })
+}
+
+// OnInput adds an input event listener, attached during the mount
+func OnInput(_evttype ick.INPUT_EVENT, _listener func(*ick.InputEvent, *ick.Element)) Item {
+	return on(func(_elem *ick.Element) { _elem.AddInputEvent(_evttype, _listener) })
+}
+
+// OnKeyboard adds a keyboard event listener, attached during the mount
+func OnKeyboard(_evttype ick.KEYBOARD_EVENT, _listener func(*ick.KeyboardEvent, *ick.Element)) Item {
+	return on(func(_elem *ick.Element) { _elem.AddKeyboard(_evttype, _listener) })
+}
+
+// OnFocus adds a focus event listener, attached during the mount
+func OnFocus(_evttype ick.FOCUS_EVENT, _listener func(*ick.FocusEvent, *ick.Element)) Item {
+	return on(func(_elem *ick.Element) { _elem.AddFocusEvent(_evttype, _listener) })
+}
+
+// On adds a generic event listener, attached during the mount
+func On(_evttype ick.GENERIC_EVENT, _listener func(*ick.Event, *ick.Element)) Item {
+	return on(func(_elem *ick.Element) { _elem.AddGenericEvent(_evttype, _listener) })
+}
+
+/******************************************************************************
+* Mount
+******************************************************************************/
+
+// Mount creates the DOM nodes of the _nodes at the end of the _parent element, and attaches their event listeners.
+// The elements are stamped with the scope of the component rendering _parent if its css is scoped.
+// _parent must be in the DOM. Returns the DOM elements of the top level element nodes.
+func Mount(_parent *ick.Element, _nodes ...Node) []*ick.Element {
+	if !_parent.IsDefined() || !_parent.IsInDOM() {
+		errors.ConsoleWarnf("Mount failed: nil element or not in DOM")
+		return nil
+	}
+	mounted := make([]*ick.Element, 0, len(_nodes))
+	for _, node := range _nodes {
+		if elem := mount(_parent, node); elem != nil {
+			mounted = append(mounted, elem)
+		}
+	}
+	ick.App.StampScope(_parent)
+	return mounted
+}
+
+// mount creates the DOM node of _node into _parent, the elements are inserted before
+// attaching their listeners and mounting their children, so they are already in the DOM.
+func mount(_parent *ick.Element, _node Node) *ick.Element {
+	switch n := _node.(type) {
+	case TextNode:
+		_parent.InsertAdjacentText(ick.WI_INSIDELAST, string(n))
+	case HTMLNode:
+		_parent.InsertAdjacentHTML(ick.WI_INSIDELAST, string(n))
+	case *ElementNode:
+		elem := ick.GetDocument().CreateElement(n.Tag)
+		if len(n.Classes) > 0 {
+			elem.SetAttribute("class", strings.Join(n.Classes, " "))
+		}
+		for _, attr := range n.Attrs {
+			elem.SetAttribute(attr.Name, attr.Value)
+		}
+		_parent.InsertAdjacentElement(ick.WI_INSIDELAST, elem)
+		for _, attach := range n.listeners {
+			attach.(func(*ick.Element))(elem)
+		}
+		for _, child := range n.Children {
+			mount(elem, child)
+		}
+		return elem
+	}
+	return nil
+}
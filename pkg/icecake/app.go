@@ -1,6 +1,7 @@
 package ick
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -18,6 +19,7 @@ var App *WebApp
 
 func init() {
 	App = NewWebApp()
+	App.hydrateOnStart()
 }
 
 // WebApp
@@ -26,6 +28,14 @@ type WebApp struct {
 
 	cmpCount    int
 	CmpRegistry map[string]*componentRegEntry
+	cmpTypes    map[reflect.Type]*componentRegEntry // the registered components by type, the type of the struct, not a pointer
+	templates   templateRegistry                    // the template functions and partials, and the parsed component templates
+	mounted     map[string]*mountedComponent        // the components rendered into the DOM, by id
+	listeners   map[string][]*Element               // the elements with listeners bound by ick-on and ick-bind attributes, by owner component id
+	bindings    map[string][]*fieldBinding          // the form fields bound by ick-bind attributes, by owner component id
+	cmpBindings map[Composer][]*fieldBinding        // the same form fields, by bound component
+	refreshing  bool                                // a refresh of the bound form fields is scheduled
+	pending     []*componentRegEntry                // the custom elements to define once the app is started
 
 	browser Window // The Global JS Window object
 }
@@ -37,6 +47,7 @@ func NewWebApp() *WebApp {
 	webapp.Document.Wrap(GetDocument())
 
 	webapp.CmpRegistry = make(map[string]*componentRegEntry, 0)
+	webapp.cmpTypes = make(map[reflect.Type]*componentRegEntry, 0)
 
 	return webapp
 }
@@ -46,11 +57,103 @@ func (_app *WebApp) Browser() *Window {
 	return &_app.browser
 }
 
+// CSPNonce returns the Content-Security-Policy nonce stamped by the spaserver on the page scripts, or an empty string.
+// Under a strict CSP, style and script elements created by the app must carry this nonce.
+func (_app *WebApp) CSPNonce() string {
+	script := _app.Call("querySelector", "script[nonce]")
+	if !script.Truthy() {
+		return ""
+	}
+	return script.GetString("nonce")
+}
+
+// Config decodes the json configuration injected by the spaserver into the page, into _v.
+// _v is usually a pointer to a struct whose fields are tagged with the names of the env variables without their prefix.
+// Values are injected as strings, so numbers and booleans require the json ",string" option:
+//
+//	var cfg struct {
+//		ApiURL string `json:"API_URL"`
+//		Beta   bool   `json:"FEATURE_BETA,string"`
+//	}
+//	err := ick.App.Config(&cfg)
+func (_app *WebApp) Config(_v any) error {
+	script := _app.Call("getElementById", "ick-config")
+	if !script.Truthy() {
+		return fmt.Errorf("app config not found: the page has no #ick-config script element")
+	}
+	if err := json.Unmarshal([]byte(script.GetString("textContent")), _v); err != nil {
+		return fmt.Errorf("app config decoding failed: %w", err)
+	}
+	return nil
+}
+
 type componentRegEntry struct {
 	ickname string
 	typ     reflect.Type
 	css     string
 	count   int
+
+	classes *Classes        // default classes of the component container, set with WithClasses
+	attrs   *Attributes     // default attributes of the component container, set with WithAttributes
+	factory func() Composer // the optional factory of new instances, set with WithFactory
+
+	element   *customElement // the native custom element backing the component, nil if not defined
+	srcCSS    string         // the css as registered, before scoping
+	scoped    bool           // the css is scoped with the scopeAttr, set with WithScopedCSS
+	dropStyle bool           // the style is removed when no instances remain, set with WithStyleRemoval
+	style     *Element       // the style element of the css in the document head, nil if not inserted
+}
+
+// initContainer sets the default classes and attributes of the component on its container _elem,
+// the registered ones first, then the init ones of the _composer instance.
+func (_cr *componentRegEntry) initContainer(_composer Composer, _elem *Element) {
+	if _cr.classes != nil {
+		_elem.Classes().SetClasses(*_cr.classes)
+	}
+	if _cr.attrs != nil {
+		_elem.Attributes().SetAttributes(*_cr.attrs)
+	}
+	if initc := _composer.GetInitClasses(); initc != nil {
+		_elem.Classes().SetClasses(*initc)
+	}
+	if inita := _composer.GetInitAttributes(); inita != nil {
+		_elem.Attributes().SetAttributes(*inita)
+	}
+}
+
+// scopeAttr returns the attribute stamped on the elements of a scoped component, ie. "data-ick-notify"
+func (_cr *componentRegEntry) scopeAttr() string {
+	return "data-" + _cr.ickname
+}
+
+// insertStyle adds the css of the component into the document head, unless it's already there
+func (_cr *componentRegEntry) insertStyle(_app *WebApp) {
+	if _cr.css == "" || _cr.style != nil {
+		return
+	}
+	style := _app.CreateElement("style")
+	if nonce := _app.CSPNonce(); nonce != "" {
+		style.Set("nonce", nonce)
+	}
+	style.SetInnerHTML(_cr.css)
+	_app.Head().AppendChild(&style.Node)
+	_cr.style = style
+}
+
+// removeStyle removes the css of the component from the document head
+func (_cr *componentRegEntry) removeStyle() {
+	if _cr.style != nil {
+		_cr.style.Remove()
+		_cr.style = nil
+	}
+}
+
+// newInstance returns a new instance of the registered component, built by the factory if any
+func (_cr *componentRegEntry) newInstance() Composer {
+	if _cr.factory != nil {
+		return _cr.factory()
+	}
+	return reflect.New(_cr.typ).Interface().(Composer)
 }
 
 func (_cr componentRegEntry) String() string {
@@ -101,18 +204,17 @@ func (_app *WebApp) RegisterComponent(_ickname string, _cmp any, _css string) er
 		count:   0,
 	}
 	_app.CmpRegistry[_ickname] = &entry
+	_app.cmpTypes[typ] = &entry
 	return errors.ConsoleLogf("RegisterComponentType: %s %q\n", _ickname, typ.String())
 }
 
+// LookupComponent returns the registration of the component of type typ, or of the type pointed to by typ.
+// Returns nil if the component is not registered.
 func (_app *WebApp) LookupComponent(typ reflect.Type) *componentRegEntry {
-	st := strings.TrimLeft(typ.String(), "*")
-	for _, v := range _app.CmpRegistry {
-		sv := strings.TrimLeft(v.typ.String(), "*")
-		if sv == st {
-			return v
-		}
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
 	}
-	return nil
+	return _app.cmpTypes[typ]
 }
 
 /*****************************************************************************/
@@ -158,23 +260,16 @@ func (_app *WebApp) CreateComponent(_composer Composer) (_id string, _newcmp *UI
 	_id, first = _app.NextComponentId(regentry.ickname)
 	_newcmp.SetId(_id)
 
-	// init classes and attributes
-	initc := _composer.GetInitClasses()
-	if initc != nil {
-		_newcmp.Classes().SetClasses(*initc)
-	}
-	inita := _composer.GetInitAttributes()
-	if inita != nil {
-		_newcmp.Attributes().SetAttributes(*inita)
-	}
+	regentry.initContainer(_composer, &_newcmp.Element)
 
 	// add css
 	fmt.Println(regentry.String(), first)
 
-	if first && regentry.css != "" {
-		style := _app.CreateElement("style")
-		style.SetInnerHTML(regentry.css)
-		_app.Head().AppendChild(&style.Node)
+	regentry.insertStyle(_app)
+
+	// stamp the root of a scoped component, its content is stamped once rendered
+	if regentry.scoped {
+		_newcmp.SetAttribute(regentry.scopeAttr(), "host")
 	}
 
 	return _id, _newcmp, _err
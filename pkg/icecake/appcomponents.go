@@ -0,0 +1,184 @@
+package ick
+
+import (
+	"reflect"
+	"sort"
+	"syscall/js"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Mounted components
+******************************************************************************/
+
+// mountedComponent is a component instance rendered into the DOM
+type mountedComponent struct {
+	cmp      Composer
+	parent   string   // the id of the closest component embedding this one, empty for a top level component
+	children []string // the ids of the components embedded in this one
+}
+
+// Component returns the mounted component with the _id, or nil if there's no such component in the DOM.
+func (_app *WebApp) Component(_id string) Composer {
+	if mounted, found := _app.mounted[_id]; found {
+		return mounted.cmp
+	}
+	return nil
+}
+
+// ParentComponent returns the closest mounted component embedding the _id component, or nil for a top level component.
+func (_app *WebApp) ParentComponent(_id string) Composer {
+	if mounted, found := _app.mounted[_id]; found {
+		return _app.Component(mounted.parent)
+	}
+	return nil
+}
+
+// ChildComponents returns the mounted components embedded in the _id component, sorted by id.
+func (_app *WebApp) ChildComponents(_id string) []Composer {
+	mounted, found := _app.mounted[_id]
+	if !found {
+		return nil
+	}
+	ids := append([]string{}, mounted.children...)
+	sort.Strings(ids)
+	cmps := make([]Composer, 0, len(ids))
+	for _, id := range ids {
+		cmps = append(cmps, _app.mounted[id].cmp)
+	}
+	return cmps
+}
+
+// ComponentsOfType returns the mounted components of the App with the T type, sorted by id:
+//
+//	for _, notif := range ick.ComponentsOfType[*ui.Notify]() {
+//		notif.Stop()
+//	}
+func ComponentsOfType[T Composer]() []T {
+	ids := make([]string, 0)
+	for id, mounted := range App.mounted {
+		if _, ok := mounted.cmp.(T); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	cmps := make([]T, 0, len(ids))
+	for _, id := range ids {
+		cmps = append(cmps, App.mounted[id].cmp.(T))
+	}
+	return cmps
+}
+
+// descendantIds returns the ids of the mounted components embedded in the _id component, recursively
+func (_app *WebApp) descendantIds(_id string) []string {
+	ids := make([]string, 0)
+	if mounted, found := _app.mounted[_id]; found {
+		for _, cid := range mounted.children {
+			ids = append(ids, cid)
+			ids = append(ids, _app.descendantIds(cid)...)
+		}
+	}
+	return ids
+}
+
+// trackComponents records the _cmps just rendered into the DOM, with their parent found in the DOM.
+// The first call starts observing the DOM to untrack the components removed from it.
+func (_app *WebApp) trackComponents(_cmps map[string]Composer) {
+	if _app.mounted == nil {
+		_app.mounted = make(map[string]*mountedComponent)
+		_app.observeRemovals()
+	}
+	for id, cmp := range _cmps {
+		_app.mounted[id] = &mountedComponent{cmp: cmp}
+	}
+	for id := range _cmps {
+		node := _app.Call("getElementById", id)
+		if !node.Truthy() {
+			continue
+		}
+		for node = node.Get("parentElement"); node.Truthy(); node = node.Get("parentElement") {
+			pid := node.GetString("id")
+			if parent, found := _app.mounted[pid]; found {
+				_app.mounted[id].parent = pid
+				parent.children = append(parent.children, id)
+				break
+			}
+		}
+	}
+}
+
+// observeRemovals starts a MutationObserver on the document, untracking the components removed from the DOM.
+// Only the components within the removed subtrees are looked up.
+func (_app *WebApp) observeRemovals() {
+	fn := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		defer func() {
+			if r := recover(); r != nil {
+				errors.ConsoleStackf(r, "Error occurs untracking removed components")
+			}
+		}()
+		_app.untrackRemovedComponents(removedIds(val(args[0])))
+		return js.Undefined()
+	})
+	observer := js.Global().Get("MutationObserver").New(fn)
+	observer.Call("observe", _app.jsvalue, map[string]any{"childList": true, "subtree": true})
+}
+
+// removedIds returns the ids of the elements of the subtrees removed by the MutationRecords _records,
+// the root elements of the subtrees included
+func removedIds(_records JSValue) []string {
+	ids := make([]string, 0)
+	for i := 0; i < _records.Length(); i++ {
+		nodes := _records.Index(i).Get("removedNodes")
+		for j := 0; j < nodes.Length(); j++ {
+			node := nodes.Index(j)
+			if node.GetInt("nodeType") != 1 {
+				continue // not an element
+			}
+			if id := node.GetString("id"); id != "" {
+				ids = append(ids, id)
+			}
+			inner := node.Call("querySelectorAll", "[id]")
+			for k := 0; k < inner.Length(); k++ {
+				ids = append(ids, inner.Index(k).GetString("id"))
+			}
+		}
+	}
+	return ids
+}
+
+// untrackRemovedComponents forgets the mounted components of the _ids whose element is no longer in the DOM,
+// releases their bound listeners, and removes the style of the components without instances left if required.
+// The elements moved within the DOM are still in it and keep their components.
+func (_app *WebApp) untrackRemovedComponents(_ids []string) {
+	removed := make(map[*componentRegEntry]bool)
+	for _, id := range _ids {
+		mounted, found := _app.mounted[id]
+		if !found || _app.Call("getElementById", id).Truthy() {
+			continue
+		}
+		delete(_app.mounted, id)
+		_app.releaseBindings(id)
+		if entry := _app.LookupComponent(reflect.TypeOf(mounted.cmp)); entry != nil && entry.dropStyle {
+			removed[entry] = true
+		}
+		if parent, found := _app.mounted[mounted.parent]; found {
+			for i, cid := range parent.children {
+				if cid == id {
+					parent.children = append(parent.children[:i], parent.children[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+
+	for _, mounted := range _app.mounted {
+		delete(removed, _app.LookupComponent(reflect.TypeOf(mounted.cmp)))
+	}
+	for entry := range removed {
+		entry.removeStyle()
+	}
+}
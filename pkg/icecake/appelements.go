@@ -0,0 +1,351 @@
+package ick
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Custom Elements
+******************************************************************************/
+
+// ConnectedCallback is implemented by the custom element components notified when their element is inserted into the DOM,
+// after their first rendering, and when it's moved within the DOM.
+type ConnectedCallback interface {
+	Connected()
+}
+
+// DisconnectedCallback is implemented by the custom element components notified when their element is removed from the DOM.
+// Unless the element is connected again right away, the component is then released with the components it embeds.
+type DisconnectedCallback interface {
+	Disconnected()
+}
+
+// AttributeChangedCallback is implemented by the custom element components handling the changes of their observed attributes.
+// The field mapped to the attribute is already updated. Without this callback the component is rendered again.
+type AttributeChangedCallback interface {
+	AttributeChanged(_name string, _oldValue string, _newValue string)
+}
+
+// customElement is the native custom element backing a registered component
+type customElement struct {
+	shadow   bool              // the template is rendered into a shadow root
+	observed map[string]string // the component fields by observed attribute name
+	names    []any             // the observed attribute names, sorted
+	bridge   js.Func           // the go function called by the custom element callbacks
+}
+
+// AsCustomElement defines the component as a native custom element, so the browser renders the <ick-name> tags
+// wherever they come from: the page html, InsertAdjacentHTML or any js code. The custom element is the container
+// of the component, and the exported string, bool and numeric fields of the component are observed as lower case attributes:
+//
+//	<ick-notify message="Saved" class="is-info"></ick-notify>
+//
+// Requires the icecake.js helpers to be loaded before the wasm app.
+func AsCustomElement() ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		if _entry.element == nil {
+			_entry.element = &customElement{}
+		}
+		return nil
+	}
+}
+
+// WithShadowDOM defines the component as a native custom element, see AsCustomElement, rendering its template
+// into an open shadow root. The component css is added to the shadow root, where :host selects the custom element.
+func WithShadowDOM() ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		_entry.element = &customElement{shadow: true}
+		return nil
+	}
+}
+
+// observedAttributes returns the exported string, bool and numeric fields of the _typ component by lower case attribute name
+func observedAttributes(_typ reflect.Type) map[string]string {
+	observed := make(map[string]string)
+	for i := 0; i < _typ.NumField(); i++ {
+		field := _typ.Field(i)
+		if !field.IsExported() || field.Anonymous {
+			continue
+		}
+		if _, err := fieldString(reflect.Zero(field.Type)); err == nil {
+			observed[strings.ToLower(field.Name)] = field.Name
+		}
+	}
+	return observed
+}
+
+// defineElement defines the custom element of the registered _entry component once the app is started,
+// when the main goroutine waits for the browser events. So the <ick-name> tags already in the page are rendered
+// with the template functions and the partials registered by main, whatever the component is registered in init.
+func (_app *WebApp) defineElement(_entry *componentRegEntry) error {
+	if js.Global().Get("ickDefineElement").Type() != js.TypeFunction {
+		return fmt.Errorf("ickDefineElement not found, icecake.js must be loaded before the wasm app")
+	}
+	if js.Global().Get("customElements").Call("get", _entry.ickname).Truthy() {
+		return fmt.Errorf("custom element already defined")
+	}
+
+	_entry.element.observed = observedAttributes(_entry.typ)
+	names := make([]any, 0, len(_entry.element.observed))
+	for name := range _entry.element.observed {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+
+	_entry.element.bridge = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		elem := CastElement(val(args[1]))
+		defer func() {
+			if r := recover(); r != nil {
+				errors.ConsoleStackf(r, "Error occurs processing %s of custom element %q", args[0].String(), _entry.ickname)
+			}
+		}()
+		switch args[0].String() {
+		case "connected":
+			_app.connectElement(_entry, elem)
+		case "disconnected":
+			if cmp, ok := _app.Component(elem.Id()).(DisconnectedCallback); ok {
+				cmp.Disconnected()
+			}
+			_app.disconnectElement(elem)
+		case "attributeChanged":
+			_app.changeElementAttribute(_entry, elem, args[2].String(), jsString(args[3]), jsString(args[4]))
+		}
+		return js.Undefined()
+	})
+	_entry.element.names = names
+
+	// js callbacks are called once the main goroutine waits
+	_app.pending = append(_app.pending, _entry)
+	if len(_app.pending) == 1 {
+		var start js.Func
+		start = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			start.Release()
+			_app.definePendingElements()
+			return js.Undefined()
+		})
+		js.Global().Call("setTimeout", start, 0)
+	}
+	return nil
+}
+
+// definePendingElements defines the custom elements registered before the app started
+func (_app *WebApp) definePendingElements() {
+	define := js.Global().Get("ickDefineElement")
+	for _, entry := range _app.pending {
+		func() {
+			// customElements.define throws if the name is already defined
+			defer func() {
+				if r := recover(); r != nil {
+					entry.element.bridge.Release()
+					errors.ConsoleErrorf("define custom element %q failed: %v", entry.ickname, r)
+				}
+			}()
+			define.Invoke(entry.ickname, entry.element.names, entry.element.bridge)
+		}()
+	}
+	_app.pending = nil
+}
+
+// jsString returns the string of _v, or an empty string if _v is null
+func jsString(_v js.Value) string {
+	if _v.Type() == js.TypeString {
+		return _v.String()
+	}
+	return ""
+}
+
+// connectElement instantiates and renders the component of the custom _elem inserted into the DOM.
+// A component moved within the DOM is only notified.
+func (_app *WebApp) connectElement(_entry *componentRegEntry, _elem *Element) {
+	id := _elem.Id()
+	if cmp := _app.Component(id); id != "" && cmp != nil {
+		if connected, ok := cmp.(ConnectedCallback); ok {
+			connected.Connected()
+		}
+		return
+	}
+
+	cmp := _entry.newInstance()
+	for attr := range _entry.element.observed {
+		if _elem.Call("hasAttribute", attr).Bool() {
+			_entry.setObservedField(cmp, attr, _elem.Call("getAttribute", attr).String())
+		}
+	}
+
+	// the custom element is the container of the component
+	_, strclasses, strattrs := cmp.Container()
+	_elem.Classes().SetClasses(*ParseClasses(strings.Trim(strclasses, " ")))
+	if attrs, err := ParseAttributes(strings.Trim(strattrs, " ")); err != nil {
+		errors.ConsoleErrorf("custom element %q: %s", _entry.ickname, err)
+	} else if attrs.Count() > 0 {
+		_elem.Attributes().SetAttributes(*attrs)
+	}
+	_entry.initContainer(cmp, _elem)
+	if id == "" {
+		id, _ = _app.NextComponentId(_entry.ickname)
+		_elem.SetId(id)
+	}
+	cmp.Wrap(_elem)
+
+	unfoldedCmps := _app.renderElement(_entry, _elem, cmp)
+	cmp.Show()
+
+	// the components embedded into a shadow root are out of the document, they're tracked as children of the element
+	shadowCmps := unfoldedCmps
+	if _entry.element.shadow {
+		unfoldedCmps = make(map[string]Composer)
+	}
+	unfoldedCmps[id] = cmp
+	_app.trackComponents(unfoldedCmps)
+	if _entry.element.shadow {
+		_app.trackShadowComponents(id, shadowCmps)
+	}
+
+	if connected, ok := cmp.(ConnectedCallback); ok {
+		connected.Connected()
+	}
+}
+
+// renderElement renders the template of the _cmp component into its custom _elem, or into its shadow root,
+// then binds the events and adds the listeners. The listeners of a previous rendering are removed first.
+// The listeners of the components embedded into a shadow root are released with the custom element.
+// Returns the embedded components.
+func (_app *WebApp) renderElement(_entry *componentRegEntry, _elem *Element, _cmp Composer) (_unfoldedCmps map[string]Composer) {
+	_unfoldedCmps = make(map[string]Composer)
+	data := TemplateData{
+		Id: _elem.Id(),
+		Me: _cmp,
+	}
+	rendered, err := unfoldComponent(_unfoldedCmps, _elem.Id(), _cmp, data, 0)
+	if err != nil {
+		return _unfoldedCmps
+	}
+
+	// the elements rendered previously are replaced
+	if _entry.element.shadow {
+		_app.releaseComponents(_app.descendantIds(_elem.Id()))
+	}
+	_app.releaseBindings(_elem.Id())
+	if listened, ok := _cmp.(interface{ RemoveListeners() }); ok {
+		listened.RemoveListeners()
+	}
+	owner := ""
+	root := JSValueProvider(GetDocument())
+	if _entry.element.shadow {
+		shadow := val(_elem.jsvalue.Get("shadowRoot"))
+		if !shadow.Truthy() {
+			shadow = _elem.Call("attachShadow", map[string]any{"mode": "open"})
+		}
+		shadow.Set("innerHTML", rendered)
+		if _entry.css != "" {
+			style := _app.CreateElement("style")
+			if nonce := _app.CSPNonce(); nonce != "" {
+				style.Set("nonce", nonce)
+			}
+			style.SetInnerHTML(_entry.srcCSS)
+			shadow.Call("prepend", style.Value())
+		}
+		root = shadow
+		owner = _elem.Id()
+	} else {
+		_entry.insertStyle(_app)
+		if _entry.scoped {
+			_elem.SetAttribute(_entry.scopeAttr(), "host")
+		}
+		_elem.SetInnerHTML(rendered)
+		stampScope(_elem, _entry, _unfoldedCmps)
+	}
+
+	showComponentsIn(root, owner, _unfoldedCmps)
+	_app.bindElements(_elem.Id(), _elem, _cmp, _unfoldedCmps)
+	_cmp.AddListeners()
+	return _unfoldedCmps
+}
+
+// changeElementAttribute updates the field mapped to the _name observed attribute of the custom _elem,
+// then notifies the component or renders it again.
+func (_app *WebApp) changeElementAttribute(_entry *componentRegEntry, _elem *Element, _name string, _oldValue string, _newValue string) {
+	// the attributes of an element not connected yet are read when it's connected
+	cmp := _app.Component(_elem.Id())
+	if cmp == nil || _oldValue == _newValue {
+		return
+	}
+	_entry.setObservedField(cmp, _name, _newValue)
+	if changed, ok := cmp.(AttributeChangedCallback); ok {
+		changed.AttributeChanged(_name, _oldValue, _newValue)
+		return
+	}
+	unfoldedCmps := _app.renderElement(_entry, _elem, cmp)
+	if _entry.element.shadow {
+		_app.trackShadowComponents(_elem.Id(), unfoldedCmps)
+	} else {
+		_app.trackComponents(unfoldedCmps)
+	}
+}
+
+// trackShadowComponents records the _cmps rendered into the shadow root of the _host custom element as its children.
+// They're out of the document, so they're released with the host only.
+func (_app *WebApp) trackShadowComponents(_host string, _cmps map[string]Composer) {
+	host, found := _app.mounted[_host]
+	if !found {
+		return
+	}
+	for id, cmp := range _cmps {
+		if id == _host {
+			continue
+		}
+		_app.mounted[id] = &mountedComponent{cmp: cmp, parent: _host}
+		host.children = append(host.children, id)
+	}
+}
+
+// disconnectElement releases the component of the custom _elem removed from the DOM, with the components it embeds,
+// its shadow root included. The release waits for the current task to end, so an element moved within the DOM,
+// connected again right away, keeps its component.
+func (_app *WebApp) disconnectElement(_elem *Element) {
+	id := _elem.Id()
+	if id == "" || _app.Component(id) == nil {
+		return
+	}
+	var release js.Func
+	release = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		release.Release()
+		if !_elem.jsvalue.Get("isConnected").Bool() {
+			_app.releaseComponents(append([]string{id}, _app.descendantIds(id)...))
+		}
+		return js.Undefined()
+	})
+	js.Global().Call("setTimeout", release, 0)
+}
+
+// releaseComponents removes the listeners of the mounted components of the _ids no longer in the document, and untracks them
+func (_app *WebApp) releaseComponents(_ids []string) {
+	for _, id := range _ids {
+		if _app.Call("getElementById", id).Truthy() {
+			continue
+		}
+		if listened, ok := _app.Component(id).(interface{ RemoveListeners() }); ok {
+			listened.RemoveListeners()
+		}
+	}
+	_app.untrackRemovedComponents(_ids)
+}
+
+// setObservedField sets the field of the _cmp component mapped to the _attr attribute, converted to the field type.
+// The value of an attribute is never trusted html, it's escaped for an HTML field.
+func (_cr *componentRegEntry) setObservedField(_cmp Composer, _attr string, _value string) {
+	field := reflect.ValueOf(_cmp).Elem().FieldByName(_cr.element.observed[_attr])
+	if field.Type() == reflect.TypeOf(HTML("")) {
+		_value = html.EscapeString(_value)
+	}
+	if err := setFieldString(field, _value); err != nil {
+		errors.ConsoleWarnf("custom element %q: attribute %q: %s", _cr.ickname, _attr, err)
+	}
+}
@@ -0,0 +1,149 @@
+package ick
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sunraylab/icecake/internal/helper"
+	"github.com/sunraylab/icecake/internal/scopedcss"
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Type-safe component registration
+******************************************************************************/
+
+// ComponentOption is an option of the component registration
+type ComponentOption func(_entry *componentRegEntry) error
+
+// WithCSS adds the _css to the document the first time the component is rendered
+func WithCSS(_css string) ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		_entry.css, _entry.srcCSS = _css, _css
+		return nil
+	}
+}
+
+// WithScopedCSS adds the _css to the document the first time the component is rendered, with its selectors
+// restricted to the elements rendered by the component. The root element of the component is selected with :host:
+//
+//	:host(.show) { opacity: 1; }
+//	.delete { float: right; }
+//
+// becomes `[data-ick-notify="host"].show { opacity: 1; } .delete[data-ick-notify] { float: right; }`.
+// The components embedded into this one are not affected.
+func WithScopedCSS(_css string) ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		css, err := scopedcss.Scope(_css, _entry.scopeAttr())
+		if err != nil {
+			return fmt.Errorf("invalid css: %w", err)
+		}
+		_entry.css, _entry.srcCSS = css, _css
+		_entry.scoped = true
+		return nil
+	}
+}
+
+// WithStyleRemoval removes the css of the component from the document when no instances remain in the DOM.
+// It's inserted again when a new instance is rendered.
+func WithStyleRemoval() ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		_entry.dropStyle = true
+		return nil
+	}
+}
+
+// WithClasses sets the default _classes of the component container, added to the ones of its Container
+func WithClasses(_classes string) ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		_entry.classes = ParseClasses(_classes)
+		return nil
+	}
+}
+
+// WithAttributes sets the default _attrs of the component container, ie. `role='alert' hidden`
+func WithAttributes(_attrs string) ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		attrs, err := ParseAttributes(_attrs)
+		if err != nil {
+			return fmt.Errorf("invalid attributes: %w", err)
+		}
+		_entry.attrs = attrs
+		return nil
+	}
+}
+
+// WithFactory sets the function building the new instances of the component embedded in templates,
+// instead of a zero value. The factory must return the registered component type.
+func WithFactory[T any, PT interface {
+	*T
+	Composer
+}](_factory func() PT) ComponentOption {
+	return func(_entry *componentRegEntry) error {
+		if typ := reflect.TypeOf((*T)(nil)).Elem(); typ != _entry.typ {
+			return fmt.Errorf("factory of %s instead of %s", typ, _entry.typ)
+		}
+		_entry.factory = func() Composer { return _factory() }
+		return nil
+	}
+}
+
+// Register registers the T component with the _ickname tag, to render it with RenderComponent
+// and to embed it into templates with <ick-name/>. The pointer to T must implement Composer,
+// which is checked at compile time:
+//
+//	err := ick.Register[ui.Notify]("ick-notify", ick.WithCSS(css), ick.WithClasses("notification"))
+//
+// Returns an error if the name is invalid or already registered, or if an option fails.
+func Register[T any, PT interface {
+	*T
+	Composer
+}](_ickname string, _options ...ComponentOption) error {
+	return App.register(reflect.TypeOf((*T)(nil)).Elem(), _ickname, _options...)
+}
+
+// MustRegister is like Register but panics if the registration fails. It's intended for the init of the component packages.
+func MustRegister[T any, PT interface {
+	*T
+	Composer
+}](_ickname string, _options ...ComponentOption) {
+	if err := Register[T, PT](_ickname, _options...); err != nil {
+		panic(err)
+	}
+}
+
+// register registers the component of type _typ with the _ickname tag
+func (_app *WebApp) register(_typ reflect.Type, _ickname string, _options ...ComponentOption) error {
+	_ickname = helper.Normalize(_ickname)
+	if !strings.HasPrefix(_ickname, "ick-") || len(_ickname) == len("ick-") {
+		return fmt.Errorf("register %q failed: name must be 'ick-' followed by the component name", _ickname)
+	}
+	if _, found := _app.CmpRegistry[_ickname]; found {
+		return fmt.Errorf("register %q failed: already registered", _ickname)
+	}
+	if entry, found := _app.cmpTypes[_typ]; found {
+		return fmt.Errorf("register %q failed: %s already registered as %q", _ickname, _typ, entry.ickname)
+	}
+
+	entry := &componentRegEntry{
+		ickname: _ickname,
+		typ:     _typ,
+	}
+	for _, option := range _options {
+		if err := option(entry); err != nil {
+			return fmt.Errorf("register %q failed: %w", _ickname, err)
+		}
+	}
+	_app.CmpRegistry[_ickname] = entry
+	_app.cmpTypes[_typ] = entry
+	if entry.element != nil {
+		if err := _app.defineElement(entry); err != nil {
+			delete(_app.CmpRegistry, _ickname)
+			delete(_app.cmpTypes, _typ)
+			return fmt.Errorf("register %q failed: %w", _ickname, err)
+		}
+	}
+	errors.ConsoleLogf("Register: %s %q\n", _ickname, _typ.String())
+	return nil
+}
@@ -0,0 +1,103 @@
+package ick
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* App templates
+******************************************************************************/
+
+// templateRegistry holds the functions and the partials shared by every component template and by RenderTemplate,
+// and caches the parsed templates of the components by type. The zero value is ready to use.
+type templateRegistry struct {
+	funcs    template.FuncMap
+	partials map[string]string                // the partial sources by name
+	base     *template.Template               // the parsed partials with the funcs, cloned by every template. nil until first use
+	cache    map[reflect.Type]*cachedTemplate // the parsed component templates
+}
+
+type cachedTemplate struct {
+	source string // the component Template() the tmpl has been parsed from
+	tmpl   *template.Template
+}
+
+// RegisterTemplateFuncs adds the _funcs to the functions available to every component template and to RenderTemplate.
+// A function with the name of a previously registered one replaces it.
+//
+//	ick.App.RegisterTemplateFuncs(template.FuncMap{
+//		"plural": func(n int, one string, many string) string { ... },
+//	})
+func (_app *WebApp) RegisterTemplateFuncs(_funcs template.FuncMap) {
+	reg := &_app.templates
+	if reg.funcs == nil {
+		reg.funcs = make(template.FuncMap)
+	}
+	for name, fn := range _funcs {
+		reg.funcs[name] = fn
+	}
+	reg.reset()
+}
+
+// RegisterPartial registers the _html template as the _name partial, available to every component template
+// and to RenderTemplate with {{template "name" .}}. The partial can use the registered functions and the other partials.
+// Returns an error if the partial can't be parsed.
+func (_app *WebApp) RegisterPartial(_name string, _html string) error {
+	reg := &_app.templates
+	if _, err := template.New(_name).Funcs(reg.funcs).Parse(_html); err != nil {
+		return errors.ConsoleErrorf("RegisterPartial %q failed: %s", _name, err.Error())
+	}
+	if reg.partials == nil {
+		reg.partials = make(map[string]string)
+	}
+	reg.partials[_name] = _html
+	reg.reset()
+	return nil
+}
+
+// reset drops the base template and the cached templates, to parse them again with the new functions and partials
+func (_reg *templateRegistry) reset() {
+	_reg.base = nil
+	_reg.cache = nil
+}
+
+// parse parses the _html template named _name, with the registered functions and partials
+func (_reg *templateRegistry) parse(_name string, _html string) (*template.Template, error) {
+	if _reg.base == nil {
+		base := template.New("").Funcs(_reg.funcs)
+		for name, partial := range _reg.partials {
+			if _, err := base.New(name).Parse(partial); err != nil {
+				return nil, fmt.Errorf("partial %q: %w", name, err)
+			}
+		}
+		_reg.base = base
+	}
+	clone, err := _reg.base.Clone()
+	if err != nil {
+		return nil, err
+	}
+	return clone.New(_name).Parse(_html)
+}
+
+// component returns the parsed template of the _cmp component, parsed once per component type.
+// The template is parsed again if the component returns another Template() than the cached one.
+func (_reg *templateRegistry) component(_cmp Composer) (*template.Template, error) {
+	typ := reflect.TypeOf(_cmp)
+	source := _cmp.Template()
+	if cached, found := _reg.cache[typ]; found && cached.source == source {
+		return cached.tmpl, nil
+	}
+	tmpl, err := _reg.parse(typ.String(), source)
+	if err != nil {
+		return nil, err
+	}
+	if _reg.cache == nil {
+		_reg.cache = make(map[reflect.Type]*cachedTemplate)
+	}
+	_reg.cache[typ] = &cachedTemplate{source: source, tmpl: tmpl}
+	return tmpl, nil
+}
@@ -258,6 +258,7 @@ func makeWindow_Generic_Event(listener func(event *Event, target *Window)) js.Fu
 		value := val(args[0])
 		evt := CastEvent(value)
 		target := CastWindow(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Window", evt.Type())
@@ -284,6 +285,7 @@ func makeWindow_BeforeUnload_Event(listener func(event *BeforeUnloadEvent, targe
 		value := val(args[0])
 		evt := CastBeforeUnloadEvent(value)
 		target := CastWindow(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Window", evt.Type())
@@ -310,6 +312,7 @@ func makeWindow_HashChange_Event(listener func(event *HashChangeEvent, target *W
 		value := val(args[0])
 		evt := CastHashChangeEvent(value)
 		target := CastWindow(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Window", evt.Type())
@@ -338,6 +341,7 @@ func makeWindow_PageTransition_Event(listener func(event *PageTransitionEvent, t
 		value := val(args[0])
 		evt := CastPageTransitionEvent(value)
 		target := CastWindow(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Window", evt.Type())
@@ -364,6 +368,7 @@ func makeWindow_UI_Event(listener func(event *UIEvent, target *Window)) js.Func
 		value := val(args[0])
 		evt := CastUIEvent(value)
 		target := CastWindow(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Window", evt.Type())
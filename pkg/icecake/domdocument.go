@@ -447,6 +447,7 @@ func makeDoc_Generic_Event(listener func(event *Event, target *Document)) js.Fun
 		value := val(args[0])
 		evt := CastEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on Document", evt.Type())
@@ -477,6 +478,7 @@ func makeDoc_Mouse_Event(listener func(event *MouseEvent, target *Document)) js.
 		value := val(args[0])
 		evt := CastMouseEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -502,6 +504,7 @@ func makeDoc_Focus_Event(listener func(event *FocusEvent, target *Document)) js.
 		value := val(args[0])
 		evt := CastFocusEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -523,6 +526,7 @@ func makeDoc_Pointer_Event(listener func(event *PointerEvent, target *Document))
 		value := val(args[0])
 		evt := CastPointerEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -544,6 +548,7 @@ func makeDoc_Input_Event(listener func(event *InputEvent, target *Document)) js.
 		value := val(args[0])
 		evt := CastInputEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -565,6 +570,7 @@ func makeDoc_Keyboard_Event(listener func(event *KeyboardEvent, target *Document
 		value := val(args[0])
 		evt := CastKeyboardEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -587,6 +593,7 @@ func makeDoc_UIEvent(listener func(event *UIEvent, target *Document)) js.Func {
 		value := val(args[0])
 		evt := CastUIEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
@@ -610,6 +617,7 @@ func makeDoc_Wheel_Event(listener func(event *WheelEvent, target *Document)) js.
 		value := val(args[0])
 		evt := CastWheelEvent(value)
 		target := CastDocument(value.Get("target"))
+		defer App.scheduleRefresh()
 		listener(evt, target)
 		return js.Undefined()
 	}
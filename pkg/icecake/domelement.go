@@ -239,6 +239,8 @@ func (_elem *Element) InnerHTML() string {
 // When writing to innerHTML, it will overwrite the content of the source element.
 // That means the HTML has to be loaded and re-parsed. This is not very efficient especially when using inside loops.
 //
+// _unsafeHtml is inserted as is, untrusted input must be sanitized first: `SetInnerHTML(sanitize.HTML(input))`.
+//
 // https://developer.mozilla.org/en-US/docs/Web/API/Element/innerHTML
 func (_elem *Element) SetInnerHTML(_unsafeHtml string) *Element {
 	if !_elem.IsDefined() {
@@ -434,6 +436,8 @@ func (_elem *Element) InsertAdjacentText(_where WHERE_INSERT, _text string) *Ele
 
 // InsertAdjacentHTML parses the specified text as HTML or XML and inserts the resulting nodes into the DOM tree at a specified position.
 //
+// _text is inserted as is, untrusted input must be sanitized first with the sanitize package.
+//
 // https://developer.mozilla.org/en-US/docs/Web/API/Element/insertAdjacentHTML
 func (_elem *Element) InsertAdjacentHTML(_where WHERE_INSERT, _text string) *Element {
 	if !_elem.IsDefined() {
@@ -714,9 +718,11 @@ func makelistenerElement_Event(listener func(event *Event, target *Element)) js.
 		value := val(args[0])
 		evt := CastEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -747,9 +753,11 @@ func makehandler_Element_Event(listener func(event *Event, target *Element)) js.
 		value := args[0]
 		evt := CastEvent(val(value))
 		target := CastElement(val(value.Get("target")))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -775,9 +783,11 @@ func makehandler_Element_MouseEvent(listener func(event *MouseEvent, target *Ele
 		value := val(args[0])
 		evt := CastMouseEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -803,9 +813,11 @@ func makelistenerElement_FocusEvent(listener func(event *FocusEvent, target *Ele
 		value := val(args[0])
 		evt := CastFocusEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -832,9 +844,11 @@ func makelistenerElement_PointerEvent(listener func(event *PointerEvent, target
 		value := val(args[0])
 		evt = CastPointerEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -860,9 +874,11 @@ func makelistenerElement_InputEvent(listener func(event *InputEvent, target *Ele
 		value := val(args[0])
 		evt := CastInputEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -888,9 +904,11 @@ func makelistenerElement_KeyboardEvent(listener func(event *KeyboardEvent, targe
 		value := val(args[0])
 		evt := CastKeyboardEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -916,9 +934,11 @@ func makelistenerElement_UIEvent(listener func(event *UIEvent, target *Element))
 		value := val(args[0])
 		evt := CastUIEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -944,9 +964,11 @@ func makeHTMLElement_WheelEvent(listener func(event *WheelEvent, target *Element
 		value := val(args[0])
 		evt := CastWheelEvent(value)
 		target := CastElement(value.Get("target"))
+		defer App.scheduleRefresh()
 		defer func() {
 			if r := recover(); r != nil {
 				errors.ConsoleStackf(r, "Error occurs processing event %q on %q id=%q", evt.Type(), target.TagName(), target.Id())
+				App.recoverBoundary(evt.Get("currentTarget"), r)
 			}
 		}()
 		listener(evt, target)
@@ -981,7 +1003,8 @@ func (_elem *Element) RenderValue(format string, _value ...any) {
 	_elem.SetInnerText(text)
 }
 
-// RenderTemplate set inner HTML with the htmlTemplate executed with the _data and unfolding components if any
+// RenderTemplate set inner HTML with the htmlTemplate executed with the _data and unfolding components if any.
+// Data values are escaped according to their context, except HTML values.
 // The element must be in the DOM to
 func (_elem *Element) RenderTemplate(_unsafeHtmlTemplate string, _data any) (_err error) {
 	if !_elem.IsDefined() || !_elem.IsInDOM() {
@@ -995,6 +1018,8 @@ func (_elem *Element) RenderTemplate(_unsafeHtmlTemplate string, _data any) (_er
 	if _err == nil {
 		_elem.SetInnerHTML(html)
 		showUnfoldedComponents(unfoldedCmps)
+		App.trackComponents(unfoldedCmps)
+		App.StampScope(_elem)
 	}
 	return _err
 }
@@ -1025,34 +1050,17 @@ func (_elem *Element) RenderComponent(_newcmp Composer, _appdata any) (_newcmpid
 		return "", errors.ConsoleErrorf("RenderComponent: failed on undefined element")
 	}
 
-	// create the HTML component into the DOM
-	_newcmpid, newcmpelem, err := App.CreateComponent(_newcmp)
+	// create the HTML component and render its template
+	_newcmpid, newcmpelem, unfoldedCmps, err := App.renderNewComponent(_newcmp, _appdata)
 	if err != nil {
-		return "", errors.ConsoleErrorf("RenderComponent:", err.Error())
-	}
-
-	// name the component
-	name := newcmpelem.TagName() + "/" + _newcmpid
-
-	// unfold and render html for a composer
-	unfoldedCmps := make(map[string]Composer, 0)
-	data := TemplateData{
-		Id:  _newcmpid,
-		Me:  _newcmp,
-		App: _appdata,
+		App.catchBoundary(_elem.Value(), err)
+		return "", fmt.Errorf("RenderComponent: %w", err)
 	}
-	// TODO: handle unfolding errors
-	html, _ := unfoldComponents(unfoldedCmps, name, _newcmp.Template(), data, 0)
-	newcmpelem.SetInnerHTML(html)
 
 	// Insert the component element into the DOM
 	_elem.PrependNodes(&newcmpelem.Node) //elem.InsertAdjacentHTML(WI_INSIDEFIRST, html)
 
-	// addlisteners
-	showUnfoldedComponents(unfoldedCmps)
-	_newcmp.AddListeners()
-
-	_newcmp.Show()
-
+	App.mountComponent(_newcmpid, &newcmpelem.Element, _newcmp, unfoldedCmps)
+	App.StampScope(_elem)
 	return _newcmpid, nil
 }
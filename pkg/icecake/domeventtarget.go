@@ -71,6 +71,7 @@ func (_evttget *EventTarget) RemoveListeners() {
 		for _, evh := range _evttget.eventHandlers {
 			evh.close()
 		}
+		_evttget.eventHandlers = nil
 	}
 }
 
@@ -0,0 +1,203 @@
+package ick
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"syscall/js"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/****************************************************************************
+* enum used by EventSource
+*****************************************************************************/
+
+type ES_READYSTATE int
+
+const (
+	ES_CONNECTING ES_READYSTATE = 0 // The connection is not yet open, or the browser is reconnecting.
+	ES_OPEN       ES_READYSTATE = 1 // The connection is open and dispatching events.
+	ES_CLOSED     ES_READYSTATE = 2 // The connection is not open, and the browser is not trying to reconnect.
+)
+
+func (_state ES_READYSTATE) String() string {
+	switch _state {
+	case ES_CONNECTING:
+		return "connecting"
+	case ES_OPEN:
+		return "open"
+	}
+	return "closed"
+}
+
+/****************************************************************************
+* ServerEvent
+*****************************************************************************/
+
+// ServerEvent is an event received from an EventSource.
+type ServerEvent struct {
+	Id   string // the last event id, sent back by the browser within the Last-Event-ID header on reconnection
+	Type string // the event name, "message" for unnamed events
+	Data string // the event data
+}
+
+// Decode unmarshals the json data of the event into _v
+func (_evt ServerEvent) Decode(_v any) error {
+	return json.Unmarshal([]byte(_evt.Data), _v)
+}
+
+/****************************************************************************
+* EventSource
+*****************************************************************************/
+
+// EventSource wraps the browser EventSource API, receiving server-sent events
+// and delivering them to a go channel.
+//
+// The browser reconnects automatically when the connection drops, sending the last received event id,
+// so the spaserver SSEBroadcaster can replay missed events.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/EventSource
+type EventSource struct {
+	EventTarget
+
+	mu     sync.Mutex
+	events chan ServerEvent
+	closed bool
+}
+
+// NewEventSource opens a connection to the _url server-sent events endpoint, and starts listening
+// to unnamed events and to events named with _eventnames.
+//
+// _url can be relative to the current page location. Returns nil if the browser can't create the EventSource.
+func NewEventSource(_url string, _eventnames ...string) *EventSource {
+	jsv, err := esNew(_url)
+	if err != nil {
+		errors.ConsoleErrorf("NewEventSource %q failed: %s", _url, err)
+		return nil
+	}
+	es := new(EventSource)
+	es.jsvalue = jsv.jsvalue
+	es.events = make(chan ServerEvent, 64)
+
+	es.AddListener(&eventHandler{eventtype: "message", jsHandler: makeEventSource_Event(es.onEvent)})
+	for _, name := range _eventnames {
+		if name == "" || name == "message" {
+			continue
+		}
+		es.AddListener(&eventHandler{eventtype: name, jsHandler: makeEventSource_Event(es.onEvent)})
+	}
+	es.AddListener(&eventHandler{eventtype: "error", jsHandler: makeEventSource_Event(es.onError)})
+	return es
+}
+
+// Events returns the channel receiving server events. The channel is closed by Close,
+// or when the browser gives up reconnecting.
+//
+// Events are dropped if the channel buffer is full, so the channel must be read continuously.
+func (_es *EventSource) Events() <-chan ServerEvent {
+	if _es == nil {
+		ch := make(chan ServerEvent)
+		close(ch)
+		return ch
+	}
+	return _es.events
+}
+
+// URL returns the absolute url of the source.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/EventSource/url
+func (_es *EventSource) URL() string {
+	if _es == nil {
+		return ""
+	}
+	return _es.GetString("url")
+}
+
+// ReadyState returns the state of the connection.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/EventSource/readyState
+func (_es *EventSource) ReadyState() ES_READYSTATE {
+	if _es == nil {
+		return ES_CLOSED
+	}
+	return ES_READYSTATE(_es.GetInt("readyState"))
+}
+
+// Close closes the connection, removes listeners and closes the Events channel.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/EventSource/close
+func (_es *EventSource) Close() {
+	if _es == nil {
+		return
+	}
+	_es.Call("close")
+	_es.shutdown()
+}
+
+func (_es *EventSource) shutdown() {
+	_es.mu.Lock()
+	defer _es.mu.Unlock()
+	if _es.closed {
+		return
+	}
+	_es.closed = true
+	_es.RemoveListeners()
+	close(_es.events)
+}
+
+func (_es *EventSource) onEvent(_event *Event) {
+	evt := ServerEvent{
+		Id:   _event.GetString("lastEventId"),
+		Type: _event.Type(),
+		Data: _event.GetString("data"),
+	}
+
+	_es.mu.Lock()
+	defer _es.mu.Unlock()
+	if _es.closed {
+		return
+	}
+	select {
+	case _es.events <- evt:
+	default:
+		errors.ConsoleWarnf("EventSource %s: events channel full, event %q dropped", _es.URL(), evt.Type)
+	}
+}
+
+// onError is fired on connection failures. The browser reconnects automatically unless the readystate is closed.
+func (_es *EventSource) onError(_event *Event) {
+	if _es.ReadyState() == ES_CLOSED {
+		errors.ConsoleWarnf("EventSource %s: connection closed", _es.URL())
+		_es.shutdown()
+		return
+	}
+	errors.ConsoleLogf("EventSource %s: connection lost, reconnecting\n", _es.URL())
+}
+
+// event attribute: Event
+func makeEventSource_Event(listener func(event *Event)) js.Func {
+	fn := func(this js.Value, args []js.Value) interface{} {
+		value := val(args[0])
+		evt := CastEvent(value)
+		defer App.scheduleRefresh()
+		defer func() {
+			if r := recover(); r != nil {
+				errors.ConsoleStackf(r, "Error occurs processing event %q on EventSource", evt.Type())
+			}
+		}()
+		listener(evt)
+		return js.Undefined()
+	}
+	return js.FuncOf(fn)
+}
+
+// esNew creates a new js EventSource, catching the js exception thrown with an invalid url.
+func esNew(_url string) (_jsv JSValue, _err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			_err = fmt.Errorf("unable to create the event source: %v", r)
+		}
+	}()
+	return val(js.Global().Get("EventSource").New(_url)), nil
+}
@@ -1,7 +1,10 @@
 package ick
 
 import (
+	"html/template"
 	"log"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -61,3 +64,141 @@ func TestRenderComponent(t *testing.T) {
 	// log.Println("------>", out)
 
 }
+
+func TestTemplateRegistry(t *testing.T) {
+	app := new(WebApp)
+	app.RegisterTemplateFuncs(template.FuncMap{"upper": strings.ToUpper})
+	if err := app.RegisterPartial("badge", `<span class="badge">{{upper .}}</span>`); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.RegisterPartial("broken", `{{.Name`); err == nil {
+		t.Errorf("partial parsing error expected")
+	}
+
+	tmpl, err := app.templates.parse("example7", `<p>{{template "badge" .Name}} {{.Name}}</p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := tmpl.Execute(&out, struct{ Name string }{Name: "<b>"}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := `<p><span class="badge">&lt;B&gt;</span> &lt;b&gt;</p>`; out.String() != expected {
+		t.Errorf("%q expected, got %q", expected, out.String())
+	}
+}
+
+func TestFieldString(t *testing.T) {
+	var data struct {
+		Name    string
+		Checked bool
+		Count   int8
+		Size    uint
+		Price   float64
+		Tags    []string
+	}
+	fields := reflect.ValueOf(&data).Elem()
+	tests := []struct {
+		field    string
+		value    string
+		expected string
+		err      bool
+	}{
+		{field: "Name", value: " Bob ", expected: " Bob "},
+		{field: "Checked", value: "true", expected: "true"},
+		{field: "Checked", value: "", expected: "false"},
+		{field: "Count", value: " -12", expected: "-12"},
+		{field: "Count", value: "300", err: true},
+		{field: "Size", value: "-1", err: true},
+		{field: "Price", value: "9.95", expected: "9.95"},
+		{field: "Price", value: "", expected: "0"},
+		{field: "Price", value: "abc", err: true},
+		{field: "Tags", value: "a", err: true},
+	}
+	for i, tst := range tests {
+		field := fields.FieldByName(tst.field)
+		err := setFieldString(field, tst.value)
+		if tst.err {
+			if err == nil {
+				t.Errorf("test %d: error expected", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: %s", i, err)
+			continue
+		}
+		if got, _ := fieldString(field); got != tst.expected {
+			t.Errorf("test %d: %q expected, got %q", i, tst.expected, got)
+		}
+	}
+}
+
+func TestObservedAttributes(t *testing.T) {
+	type element struct {
+		UIComponent
+		Message string
+		Active  bool
+		Count   int
+		Ratio   float32
+		Tags    []string
+		hidden  string
+	}
+	observed := observedAttributes(reflect.TypeOf(element{}))
+	expected := map[string]string{"message": "Message", "active": "Active", "count": "Count", "ratio": "Ratio"}
+	if !reflect.DeepEqual(observed, expected) {
+		t.Errorf("%v expected, got %v", expected, observed)
+	}
+}
+
+type testPanicking struct{ UIComponent }
+
+func (c *testPanicking) Template() string { panic("template failure") }
+
+type testBoundary struct{ UIComponent }
+
+func (c *testBoundary) Template() string { return `<div><ick-test-panicking/></div>` }
+func (c *testBoundary) Fallback() string { return `<p>{{.Id}}: {{.Err}}</p>` }
+
+func TestErrorBoundary(t *testing.T) {
+	if err := App.register(reflect.TypeOf(testPanicking{}), "ick-test-panicking"); err != nil {
+		t.Fatal(err)
+	}
+	if err := App.register(reflect.TypeOf(testBoundary{}), "ick-test-boundary"); err != nil {
+		t.Fatal(err)
+	}
+
+	unfoldedCmps := make(map[string]Composer, 0)
+	out, err := unfoldComponent(unfoldedCmps, "boundary", &testBoundary{}, TemplateData{Id: "ick-test-boundary-1"}, 0)
+	if err != nil {
+		t.Fatalf("fallback expected, got error %s", err)
+	}
+	if !strings.HasPrefix(out, `<p>ick-test-boundary-1: rendering`) || !strings.Contains(out, "template failure") {
+		t.Errorf("fallback expected, got %q", out)
+	}
+	if len(unfoldedCmps) != 0 {
+		t.Errorf("no unfolded components expected, got %v", unfoldedCmps)
+	}
+
+	if _, err := unfoldComponent(unfoldedCmps, "panicking", &testPanicking{}, TemplateData{}, 0); err == nil {
+		t.Errorf("error expected")
+	}
+}
+
+func TestLongestIncreasing(t *testing.T) {
+	tests := []struct {
+		seq  []int
+		stay []bool
+	}{
+		{seq: []int{1, 2, 3, 0}, stay: []bool{true, true, true, false}},
+		{seq: []int{3, 0, 1, 2}, stay: []bool{false, true, true, true}},
+		{seq: []int{-1, 0, -1, 2, 1}, stay: []bool{false, true, false, false, true}},
+		{seq: []int{2, 1, 0}, stay: []bool{false, false, true}},
+		{seq: []int{}, stay: []bool{}},
+	}
+	for _, tst := range tests {
+		if got := longestIncreasing(tst.seq); !reflect.DeepEqual(got, tst.stay) {
+			t.Errorf("%v: %v expected, got %v", tst.seq, tst.stay, got)
+		}
+	}
+}
@@ -75,3 +75,9 @@ type Composer interface {
 	Show()
 	Hide()
 }
+
+// HTMLRenderer is implemented by components with an .ick.html template compiled by "icecake gen templates".
+// The compiled RenderHTML is used instead of the Template string, which is then never parsed at runtime.
+type HTMLRenderer interface {
+	RenderHTML(_data TemplateData) (_html string, _err error)
+}
@@ -0,0 +1,284 @@
+package ick
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"strconv"
+	"strings"
+	"syscall/js"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Two-way field binding
+******************************************************************************/
+
+// the template attribute binding a form field to a component field
+const fieldBindingAttr = "ick-bind"
+
+// fieldBinding keeps a form field in sync with a field of a component
+type fieldBinding struct {
+	elem     *Element // the input, select or textarea element
+	cmp      Composer // the component owning the field
+	field    string   // the name of the component field
+	rendered string   // the value of the component field when the form field has been rendered or read
+}
+
+// bindField binds the _elem form field to the _field of the _cmp component: the form field is rendered with the
+// component field, which is updated on every input of the user. Checkboxes are bound to bool fields, radio buttons
+// select the field value matching their value, other form fields are bound to their value converted to the field type.
+//
+// The binding is recorded with the _owner id, to be released when the owner is removed from the DOM.
+// The form field is rendered again when the component field changes, see RefreshBindings.
+func (_app *WebApp) bindField(_owner string, _elem *Element, _cmp Composer, _field string) error {
+	bnd := &fieldBinding{elem: _elem, cmp: _cmp, field: _field}
+	if err := bnd.render(); err != nil {
+		return err
+	}
+
+	event := GENERIC_EVENT("input")
+	switch bnd.kind() {
+	case "checkbox", "radio", "select":
+		event = GENERIC_EVENT("change")
+	}
+	_elem.AddGenericEvent(event, func(*Event, *Element) {
+		if err := bnd.update(); err != nil {
+			errors.ConsoleWarnf("%s=%q: %s", fieldBindingAttr, bnd.field, err)
+			return
+		}
+		_app.refreshBindings(bnd.cmp, bnd)
+	})
+
+	if _app.bindings == nil {
+		_app.bindings = make(map[string][]*fieldBinding)
+		_app.cmpBindings = make(map[Composer][]*fieldBinding)
+	}
+	_app.bindings[_owner] = append(_app.bindings[_owner], bnd)
+	_app.cmpBindings[_cmp] = append(_app.cmpBindings[_cmp], bnd)
+	return nil
+}
+
+// unbindField forgets the _bnd binding of its component
+func (_app *WebApp) unbindField(_bnd *fieldBinding) {
+	bindings := _app.cmpBindings[_bnd.cmp]
+	for i, bnd := range bindings {
+		if bnd == _bnd {
+			bindings = append(bindings[:i], bindings[i+1:]...)
+			break
+		}
+	}
+	if len(bindings) == 0 {
+		delete(_app.cmpBindings, _bnd.cmp)
+	} else {
+		_app.cmpBindings[_bnd.cmp] = bindings
+	}
+}
+
+// RefreshBindings renders the form fields bound with ick-bind to the fields of the _cmp component.
+// The bound form fields are refreshed after every event handler of the App, RefreshBindings is only required
+// after updating the fields of a mounted component out of an event handler, like in a goroutine.
+func (_app *WebApp) RefreshBindings(_cmp Composer) {
+	_app.refreshBindings(_cmp, nil)
+}
+
+// refreshBindings renders the form fields bound to the _cmp component, except the _source one
+func (_app *WebApp) refreshBindings(_cmp Composer, _source *fieldBinding) {
+	for _, bnd := range _app.cmpBindings[_cmp] {
+		if bnd == _source {
+			continue
+		}
+		if err := bnd.render(); err != nil {
+			errors.ConsoleWarnf("%s=%q: %s", fieldBindingAttr, bnd.field, err)
+		}
+	}
+}
+
+// scheduleRefresh refreshes the bound form fields whose component field changed, once the current event handlers end.
+// Called by every event handler, the refresh is scheduled once per tick of the event loop.
+func (_app *WebApp) scheduleRefresh() {
+	if _app.refreshing || len(_app.cmpBindings) == 0 {
+		return
+	}
+	_app.refreshing = true
+	var refresh js.Func
+	refresh = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		refresh.Release()
+		_app.refreshing = false
+		for _, bindings := range _app.cmpBindings {
+			for _, bnd := range bindings {
+				if !bnd.changed() {
+					continue
+				}
+				if err := bnd.render(); err != nil {
+					errors.ConsoleWarnf("%s=%q: %s", fieldBindingAttr, bnd.field, err)
+				}
+			}
+		}
+		return js.Undefined()
+	})
+	js.Global().Call("setTimeout", refresh, 0)
+}
+
+// kind returns the kind of the bound form field: checkbox, radio, select or value
+func (_bnd *fieldBinding) kind() string {
+	switch strings.ToLower(_bnd.elem.TagName()) {
+	case "select":
+		return "select"
+	case "input":
+		if typ := strings.ToLower(_bnd.elem.GetString("type")); typ == "checkbox" || typ == "radio" {
+			return typ
+		}
+	}
+	return "value"
+}
+
+// value returns the bound component field
+func (_bnd *fieldBinding) value() (reflect.Value, error) {
+	cmp := reflect.ValueOf(_bnd.cmp)
+	if cmp.Kind() != reflect.Pointer || cmp.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("%s is not a pointer to a struct", cmp.Type())
+	}
+	field := cmp.Elem().FieldByName(_bnd.field)
+	if !field.IsValid() || !field.CanSet() {
+		return reflect.Value{}, fmt.Errorf("%s has no exported field %q", cmp.Type(), _bnd.field)
+	}
+	return field, nil
+}
+
+// changed reports whether the component field changed since the form field has been rendered or read
+func (_bnd *fieldBinding) changed() bool {
+	field, err := _bnd.value()
+	if err != nil {
+		return false
+	}
+	value, err := fieldString(field)
+	return err == nil && value != _bnd.rendered
+}
+
+// render renders the form field with the component field
+func (_bnd *fieldBinding) render() error {
+	field, err := _bnd.value()
+	if err != nil {
+		return err
+	}
+	if _bnd.kind() == "checkbox" {
+		if field.Kind() != reflect.Bool {
+			return fmt.Errorf("a checkbox must be bound to a bool field, not %s", field.Type())
+		}
+		_bnd.elem.Set("checked", field.Bool())
+		_bnd.rendered = strconv.FormatBool(field.Bool())
+		return nil
+	}
+
+	value, err := fieldString(field)
+	if err != nil {
+		return err
+	}
+	_bnd.rendered = value
+	if field.Type() == reflect.TypeOf(HTML("")) {
+		value = html.UnescapeString(value)
+	}
+	if _bnd.kind() == "radio" {
+		_bnd.elem.Set("checked", value == _bnd.elem.GetString("value"))
+	} else {
+		_bnd.elem.Set("value", value)
+	}
+	return nil
+}
+
+// update updates the component field with the form field
+func (_bnd *fieldBinding) update() error {
+	field, err := _bnd.value()
+	if err != nil {
+		return err
+	}
+	switch _bnd.kind() {
+	case "checkbox":
+		field.SetBool(_bnd.elem.GetBool("checked"))
+		_bnd.rendered = strconv.FormatBool(field.Bool())
+		return nil
+	case "radio":
+		if !_bnd.elem.GetBool("checked") {
+			return nil
+		}
+	}
+
+	// the value of a form field is never trusted html
+	value := _bnd.elem.GetString("value")
+	if field.Type() == reflect.TypeOf(HTML("")) {
+		value = html.EscapeString(value)
+	}
+	if err := setFieldString(field, value); err != nil {
+		return err
+	}
+	// the form field is kept as typed by the user
+	_bnd.rendered, _ = fieldString(field)
+	return nil
+}
+
+// fieldString returns the string of the _field value, for string, bool and numeric fields
+func fieldString(_field reflect.Value) (string, error) {
+	switch _field.Kind() {
+	case reflect.String:
+		return _field.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(_field.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(_field.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(_field.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(_field.Float(), 'f', -1, _field.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unmanaged field type %s", _field.Type())
+}
+
+// setFieldString sets the _field with the _value converted to the field type, for string, bool and numeric fields.
+// An empty _value sets a bool or numeric field to its zero value.
+func setFieldString(_field reflect.Value, _value string) error {
+	if _field.Kind() == reflect.String {
+		_field.SetString(_value)
+		return nil
+	}
+	if strings.TrimSpace(_value) == "" {
+		switch _field.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+			_field.Set(reflect.Zero(_field.Type()))
+			return nil
+		}
+	}
+
+	_value = strings.TrimSpace(_value)
+	switch _field.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(_value)
+		if err != nil {
+			return fmt.Errorf("invalid bool %q", _value)
+		}
+		_field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(_value, 10, _field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", _value)
+		}
+		_field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(_value, 10, _field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", _value)
+		}
+		_field.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(_value, _field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", _value)
+		}
+		_field.SetFloat(f)
+	default:
+		return fmt.Errorf("unmanaged field type %s", _field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package ick
+
+import (
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"strings"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Error boundaries
+******************************************************************************/
+
+// ErrorBoundary is implemented by the components catching the errors and the panics of their rendering,
+// of the rendering of the components they embed, and of the event handlers of their elements.
+// The Fallback template is then rendered into the boundary instead of its content, with a FallbackData:
+//
+//	func (c *Panel) Fallback() string {
+//		return `<p class="notification is-danger">Unable to display the panel: {{.Err}}</p>`
+//	}
+//
+// The fallback template can use the App template functions and partials, but can't embed components.
+type ErrorBoundary interface {
+	Composer
+	Fallback() (_html string)
+}
+
+// FallbackData is the data the Fallback template of an ErrorBoundary is rendered with
+type FallbackData struct {
+	Id  string // the id of the boundary component
+	Me  any    // the boundary component
+	Err error  // the error caught
+}
+
+// renderError is an error of the rendering of a component, already reported with its stacktrace
+type renderError struct{ error }
+
+// recoverRendering reports the _r panic recovered while rendering the _name component, with its stacktrace,
+// and returns it as an error
+func recoverRendering(_name string, _r any) error {
+	errors.ConsoleStackf(_r, "Error occurs rendering %q", _name)
+	return renderError{fmt.Errorf("rendering %q panicked: %v", _name, _r)}
+}
+
+// reportRendering reports the _err returned by the rendering of the _id component named _name, with its stacktrace,
+// unless it has already been reported by the rendering of an embedded component.
+func reportRendering(_id string, _name string, _err error) error {
+	if _, reported := _err.(renderError); reported {
+		return _err
+	}
+	errors.ConsoleErrorf("Error occurs rendering %q id=%q: %s", _name, _id, _err)
+	errors.ConsoleLogf("> rendering stacktrace:\n" + string(debug.Stack()))
+	return renderError{fmt.Errorf("rendering %q: %w", _name, _err)}
+}
+
+// renderFallback reports the _err caught by the _id _boundary component and returns its rendered fallback
+func renderFallback(_id string, _boundary ErrorBoundary, _err error) string {
+	errors.ConsoleErrorf("error boundary %q caught: %s", _id, _err)
+	tmpl, err := App.templates.parse(reflect.TypeOf(_boundary).String()+"/fallback", _boundary.Fallback())
+	if err == nil {
+		var out strings.Builder
+		data := FallbackData{
+			Id:  _id,
+			Me:  _boundary,
+			Err: _err,
+		}
+		if err = tmpl.Execute(&out, data); err == nil {
+			return out.String()
+		}
+	}
+	errors.ConsoleErrorf("error boundary %q: fallback failed: %s", _id, err)
+	return ""
+}
+
+// recoverBoundary renders the fallback of the closest mounted error boundary around the _target of an event handler
+// which panicked with _r. Does nothing if the target is not embedded into an error boundary.
+func (_app *WebApp) recoverBoundary(_target JSValue, _r any) {
+	_app.catchBoundary(_target, fmt.Errorf("event handler panicked: %v", _r))
+}
+
+// catchBoundary renders the fallback of the closest mounted error boundary around the _target element, _target included,
+// with the _err. The components embedded in the boundary are released and forgotten.
+// Returns false if the target is not embedded into an error boundary.
+func (_app *WebApp) catchBoundary(_target JSValue, _err error) bool {
+	for node := _target; node.Truthy(); node = node.Get("parentElement") {
+		id := node.GetString("id")
+		mounted, found := _app.mounted[id]
+		if !found {
+			continue
+		}
+		if boundary, ok := mounted.cmp.(ErrorBoundary); ok {
+			descendants := _app.descendantIds(id)
+			_app.releaseBindings(id)
+			CastElement(node).SetInnerHTML(renderFallback(id, boundary, _err))
+			_app.untrackRemovedComponents(descendants)
+			return true
+		}
+	}
+	return false
+}
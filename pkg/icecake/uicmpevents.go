@@ -0,0 +1,113 @@
+package ick
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Declarative event and field binding
+******************************************************************************/
+
+// the prefix of the template attributes binding an event to a component method
+const eventBindingPrefix = "ick-on:"
+
+// bindElements binds the `ick-on:event="Method"` and `ick-bind="Field"` attributes of the elements rendered
+// by the _cmp component, its _root element included, to the methods and the fields of the component.
+// The elements of the components embedded into this one are not affected. The attributes are removed once bound.
+//
+// The event listener depends on the signature of the method:
+//
+//	func(*ick.MouseEvent, *ick.Element)
+//	func(*ick.InputEvent, *ick.Element)
+//	func(*ick.KeyboardEvent, *ick.Element)
+//	func(*ick.FocusEvent, *ick.Element)
+//	func(*ick.PointerEvent, *ick.Element)
+//	func(*ick.Event, *ick.Element)
+//	func()
+//
+// The listeners are recorded with the _owner id, to be released when the owner is removed from the DOM.
+func (_app *WebApp) bindElements(_owner string, _root *Element, _cmp Composer, _unfoldedCmps map[string]Composer) {
+	var bind func(_elem JSValue)
+	bind = func(_elem JSValue) {
+		attrs := _elem.Get("attributes")
+		names := make([]string, 0)
+		for i := 0; i < attrs.Length(); i++ {
+			if name := attrs.Index(i).GetString("name"); strings.HasPrefix(name, eventBindingPrefix) || name == fieldBindingAttr {
+				names = append(names, name)
+			}
+		}
+		if len(names) > 0 {
+			elem := CastElement(_elem)
+			for _, name := range names {
+				value := elem.Call("getAttribute", name).String()
+				elem.Call("removeAttribute", name)
+				var err error
+				if name == fieldBindingAttr {
+					err = _app.bindField(_owner, elem, _cmp, value)
+				} else {
+					err = bindEvent(elem, strings.TrimPrefix(name, eventBindingPrefix), _cmp, value)
+				}
+				if err != nil {
+					errors.ConsoleErrorf("%s on %q id=%q: %s", name, elem.TagName(), elem.Id(), err)
+				}
+			}
+			_app.listeners[_owner] = append(_app.listeners[_owner], elem)
+		}
+
+		children := _elem.Get("children")
+		for i := 0; i < children.Length(); i++ {
+			child := children.Index(i)
+			if _, embedded := _unfoldedCmps[child.GetString("id")]; embedded {
+				continue
+			}
+			bind(child)
+		}
+	}
+	if _app.listeners == nil {
+		_app.listeners = make(map[string][]*Element)
+	}
+	bind(_root.JSValue)
+}
+
+// bindEvent adds a listener of the _event to _elem calling the _method of the _cmp component
+func bindEvent(_elem *Element, _event string, _cmp Composer, _method string) error {
+	fn := reflect.ValueOf(_cmp).MethodByName(_method)
+	if !fn.IsValid() {
+		return fmt.Errorf("%s has no method %q", reflect.TypeOf(_cmp), _method)
+	}
+	switch listener := fn.Interface().(type) {
+	case func(*MouseEvent, *Element):
+		_elem.AddMouseEvent(MOUSE_EVENT(_event), listener)
+	case func(*InputEvent, *Element):
+		_elem.AddInputEvent(INPUT_EVENT(_event), listener)
+	case func(*KeyboardEvent, *Element):
+		_elem.AddKeyboard(KEYBOARD_EVENT(_event), listener)
+	case func(*FocusEvent, *Element):
+		_elem.AddFocusEvent(FOCUS_EVENT(_event), listener)
+	case func(*PointerEvent, *Element):
+		_elem.AddPointerEvent(POINTER_EVENT(_event), listener)
+	case func(*Event, *Element):
+		_elem.AddGenericEvent(GENERIC_EVENT(_event), listener)
+	case func():
+		_elem.AddGenericEvent(GENERIC_EVENT(_event), func(*Event, *Element) { listener() })
+	default:
+		return fmt.Errorf("method %q has an unexpected signature %s", _method, fn.Type())
+	}
+	return nil
+}
+
+// releaseBindings removes the listeners and the field bindings of the elements of the _owner component, and releases them
+func (_app *WebApp) releaseBindings(_owner string) {
+	for _, elem := range _app.listeners[_owner] {
+		elem.RemoveListeners()
+	}
+	delete(_app.listeners, _owner)
+	for _, bnd := range _app.bindings[_owner] {
+		_app.unbindField(bnd)
+	}
+	delete(_app.bindings, _owner)
+}
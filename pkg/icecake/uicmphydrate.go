@@ -0,0 +1,92 @@
+package ick
+
+import (
+	"reflect"
+	"syscall/js"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Prerendered components
+******************************************************************************/
+
+// the attributes of the elements rendered by the static site export
+const (
+	prerenderedAttr      = "data-ick-prerendered" // the content of the element is already rendered
+	prerenderedComponent = "data-ick-component"   // the name of the component rendered into the container
+	prerenderedTagAttrs  = "data-ick-attrs"       // the attributes of the <ick-*/> tag of the component
+)
+
+// Hydrate instantiates the components prerendered by the static site export into the _root element, and mounts them
+// on their existing DOM: their listeners are added, but they're not rendered again.
+// Returns false if the content of the _root element has not been prerendered, to be rendered by the app.
+//
+// The prerendered components of the page are hydrated once the app is started, Hydrate is only required
+// before rendering an element whose content may have been prerendered, as markdown.RenderMarkdown does.
+func (_app *WebApp) Hydrate(_root *Element) bool {
+	if !_root.IsDefined() || !_root.Call("hasAttribute", prerenderedAttr).Bool() {
+		return false
+	}
+	// the content is rendered by the app afterwards
+	_root.Call("removeAttribute", prerenderedAttr)
+	_app.hydrateComponents(_root.Value())
+	return true
+}
+
+// hydrateOnStart hydrates the prerendered components of the page once the app is started,
+// when the main goroutine waits for the browser events and the components are registered.
+func (_app *WebApp) hydrateOnStart() {
+	var start js.Func
+	start = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		start.Release()
+		defer func() {
+			if r := recover(); r != nil {
+				errors.ConsoleStackf(r, "Error occurs hydrating the prerendered components")
+			}
+		}()
+		_app.hydrateComponents(_app.Value())
+		return js.Undefined()
+	})
+	js.Global().Call("setTimeout", start, 0)
+}
+
+// hydrateComponents instantiates the prerendered components within _root, which are not mounted yet,
+// feeds their fields with the attributes of their tag, then mounts them on their container.
+func (_app *WebApp) hydrateComponents(_root JSValue) {
+	hydrated := make(map[string]Composer)
+	nodes := _root.Call("querySelectorAll", "["+prerenderedComponent+"]")
+	for i := 0; i < nodes.Length(); i++ {
+		elem := CastElement(nodes.Index(i))
+		id := elem.Id()
+		if _, mounted := _app.mounted[id]; id == "" || mounted {
+			continue
+		}
+		name := elem.Call("getAttribute", prerenderedComponent).String()
+		entry := _app.CmpRegistry[name]
+		if entry == nil {
+			errors.ConsoleWarnf("hydrating %q: non registered component %q", id, name)
+			continue
+		}
+		attrs, err := ParseAttributes(jsString(elem.Call("getAttribute", prerenderedTagAttrs).jsvalue))
+		if err != nil {
+			errors.ConsoleWarnf("hydrating %q: %s", id, err)
+			continue
+		}
+		attrs.SetAttribute("id", id)
+
+		cmp := entry.newInstance()
+		setComponentAttributes(id, cmp, elem, attrs)
+		entry.insertStyle(_app)
+		hydrated[id] = cmp
+	}
+	if len(hydrated) == 0 {
+		return
+	}
+
+	for id, cmp := range hydrated {
+		stampScope(CastElement(_app.Call("getElementById", id)), _app.LookupComponent(reflect.TypeOf(cmp)), hydrated)
+	}
+	showUnfoldedComponents(hydrated)
+	_app.trackComponents(hydrated)
+}
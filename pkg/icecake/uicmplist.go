@@ -0,0 +1,218 @@
+package ick
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/*****************************************************************************
+* Keyed lists
+******************************************************************************/
+
+// RowComposer is implemented by the row components of a List, receiving their item before every rendering
+type RowComposer[T any] interface {
+	Composer
+	SetItem(_item T)
+}
+
+// List renders the items of a slice into a container element, one row component per item.
+// Rows are identified by the key of their item, so updating the list inserts, moves and removes only the changed rows.
+// The row component instances, their DOM and their listeners stay alive across updates.
+type List[T any] struct {
+	container *Element
+	key       func(T) string
+	rowType   reflect.Type
+	appdata   any
+	rows      map[string]*listRow[T] // the rendered rows by key
+}
+
+// listRow is a row rendered in a List
+type listRow[T any] struct {
+	id       string
+	cmp      RowComposer[T]
+	elem     *Element
+	item     T
+	unfolded map[string]Composer // the components embedded in the row, until it's mounted
+}
+
+// NewList returns a List rendering the items into the _container with a row component of type R, identified by the _key of their item.
+// The container is dedicated to the list, it must not have other children. R must be registered:
+//
+//	ick.MustRegister[TodoRow]("ick-todo-row")
+//	todos := ick.NewList[Todo, TodoRow](ick.App.ChildById("todos"), func(t Todo) string { return t.Id }, nil)
+//	err := todos.Update(items)
+//
+// _appdata is passed to the row templates as {{.App}}.
+func NewList[T any, R any, PR interface {
+	*R
+	RowComposer[T]
+}](_container *Element, _key func(T) string, _appdata any) *List[T] {
+	return &List[T]{
+		container: _container,
+		key:       _key,
+		rowType:   reflect.TypeOf((*R)(nil)).Elem(),
+		appdata:   _appdata,
+		rows:      make(map[string]*listRow[T]),
+	}
+}
+
+// Update renders the _items into the container of the list, in their order. The rows of new keys are inserted,
+// the rows of removed keys are removed, and the fewest rows are moved to keep the others in place.
+// The rows whose item changed receive their new item and are rendered again.
+//
+// Returns an error if two items have the same key, or if a new row can't be rendered. The list is then left unchanged,
+// the rendering error being given to the closest error boundary embedding the container.
+func (_list *List[T]) Update(_items []T) error {
+	if !_list.container.IsDefined() || !_list.container.IsInDOM() {
+		return errors.ConsoleErrorf("List.Update failed: nil container or not in DOM")
+	}
+
+	keys := make([]string, len(_items))
+	found := make(map[string]bool, len(_items))
+	for i, item := range _items {
+		keys[i] = _list.key(item)
+		if found[keys[i]] {
+			return errors.ConsoleErrorf("List.Update failed: duplicate key %q", keys[i])
+		}
+		found[keys[i]] = true
+	}
+
+	// create and render the new rows first, so the list is left unchanged if one of them fails
+	rows := make([]*listRow[T], len(_items))
+	for i, item := range _items {
+		row, exists := _list.rows[keys[i]]
+		if !exists {
+			var err error
+			if row, err = _list.newRow(item); err != nil {
+				App.catchBoundary(_list.container.Value(), err)
+				return errors.ConsoleErrorf("List.Update failed: %s", err)
+			}
+		}
+		rows[i] = row
+	}
+
+	// remove the rows whose key is gone, their element may have already been removed from the DOM
+	for key, row := range _list.rows {
+		if !found[key] {
+			if listened, ok := row.cmp.(interface{ RemoveListeners() }); ok {
+				listened.RemoveListeners()
+			}
+			row.elem.Remove()
+			delete(_list.rows, key)
+		}
+	}
+
+	// the sequence of the current positions of the rows in the container, -1 for the rows not in the container
+	positions := make(map[string]int, len(_list.rows))
+	children := _list.container.Get("children")
+	for i := 0; i < children.Length(); i++ {
+		positions[children.Index(i).GetString("id")] = i
+	}
+	current := make([]int, len(_items))
+	for i, row := range rows {
+		current[i] = -1
+		if pos, found := positions[row.id]; found {
+			current[i] = pos
+		}
+	}
+
+	// the rows of the longest increasing subsequence of positions stay in place, the others are inserted
+	// before the next row, from the last one
+	stay := longestIncreasing(current)
+	var next *Element
+	for i := len(rows) - 1; i >= 0; i-- {
+		if !stay[i] {
+			if next == nil {
+				_list.container.AppendChild(&rows[i].elem.Node)
+			} else {
+				_list.container.InsertBefore(&rows[i].elem.Node, &next.Node)
+			}
+		}
+		next = rows[i].elem
+	}
+
+	// mount the new rows and update the others
+	inserted := false
+	for i, item := range _items {
+		row, exists := _list.rows[keys[i]]
+		if !exists {
+			row = rows[i]
+			_list.rows[keys[i]] = row
+			App.mountComponent(row.id, row.elem, row.cmp, row.unfolded)
+			row.unfolded = nil
+			inserted = true
+		} else if !reflect.DeepEqual(row.item, item) {
+			row.item = item
+			row.cmp.SetItem(item)
+			App.rerenderComponent(row.id, row.elem, row.cmp, _list.appdata)
+		}
+	}
+	if inserted {
+		App.StampScope(_list.container)
+	}
+	return nil
+}
+
+// longestIncreasing returns the positions of the values of _seq within its longest increasing subsequence,
+// ignoring the negative values
+func longestIncreasing(_seq []int) []bool {
+	in := make([]bool, len(_seq))
+	tails := make([]int, 0, len(_seq)) // the position of the smallest last value of the subsequences, by length
+	prev := make([]int, len(_seq))     // the position of the previous value within the subsequence
+	for i, v := range _seq {
+		if v < 0 {
+			continue
+		}
+		n := sort.Search(len(tails), func(j int) bool { return _seq[tails[j]] >= v })
+		prev[i] = -1
+		if n > 0 {
+			prev[i] = tails[n-1]
+		}
+		if n == len(tails) {
+			tails = append(tails, i)
+		} else {
+			tails[n] = i
+		}
+	}
+	if len(tails) > 0 {
+		for i := tails[len(tails)-1]; i >= 0; i = prev[i] {
+			in[i] = true
+		}
+	}
+	return in
+}
+
+// Len returns the number of rows of the list
+func (_list *List[T]) Len() int {
+	return len(_list.rows)
+}
+
+// Row returns the row component of the item with the _key, or nil if there's no such item in the list
+func (_list *List[T]) Row(_key string) RowComposer[T] {
+	if row, found := _list.rows[_key]; found {
+		return row.cmp
+	}
+	return nil
+}
+
+// newRow creates a row component for the _item and renders it, the row is not inserted into the DOM yet
+func (_list *List[T]) newRow(_item T) (*listRow[T], error) {
+	entry := App.LookupComponent(_list.rowType)
+	if entry == nil {
+		return nil, fmt.Errorf("non registered row component %q", _list.rowType.String())
+	}
+	cmp, ok := entry.newInstance().(RowComposer[T])
+	if !ok {
+		return nil, fmt.Errorf("the factory of %q does not return a row component", entry.ickname)
+	}
+	cmp.SetItem(_item)
+
+	id, elem, unfoldedCmps, err := App.renderNewComponent(cmp, _list.appdata)
+	if err != nil {
+		return nil, err
+	}
+	return &listRow[T]{id: id, cmp: cmp, elem: &elem.Element, item: _item, unfolded: unfoldedCmps}, nil
+}
@@ -0,0 +1,95 @@
+package ick
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/sunraylab/icecake/pkg/sanitize"
+)
+
+/******************************************************************************
+* helpers of the templates compiled by "icecake gen templates"
+******************************************************************************/
+
+var reflectErrorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// TemplateField returns the _name field, map entry or method result of the untyped _v value,
+// the way text/template evaluates {{.App.Name}}. Compiled templates use it for values of type any,
+// the other fields are type-checked at build time.
+func TemplateField(_v any, _name string) (_field any, _err error) {
+	v := reflect.ValueOf(_v)
+	if !v.IsValid() {
+		return nil, fmt.Errorf("nil data; no entry for key %q", _name)
+	}
+	if method := v.MethodByName(_name); method.IsValid() {
+		return callTemplateMethod(method, _name)
+	}
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("nil pointer evaluating %s.%s", v.Type(), _name)
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if field, found := v.Type().FieldByName(_name); found {
+			if !field.IsExported() {
+				return nil, fmt.Errorf("%s is an unexported field of struct type %s", _name, v.Type())
+			}
+			return v.FieldByIndex(field.Index).Interface(), nil
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			entry := v.MapIndex(reflect.ValueOf(_name).Convert(v.Type().Key()))
+			if !entry.IsValid() {
+				return nil, nil
+			}
+			return entry.Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("can't evaluate field %s in type %s", _name, v.Type())
+}
+
+func callTemplateMethod(_method reflect.Value, _name string) (any, error) {
+	typ := _method.Type()
+	if typ.NumIn() != 0 || typ.NumOut() == 0 || typ.NumOut() > 2 || typ.NumOut() == 2 && typ.Out(1) != reflectErrorType {
+		return nil, fmt.Errorf("can't call method %s of type %s in a template", _name, typ)
+	}
+	out := _method.Call(nil)
+	if len(out) == 2 && !out[1].IsNil() {
+		return nil, out[1].Interface().(error)
+	}
+	return out[0].Interface(), nil
+}
+
+// TemplateTruth reports whether the untyped _v value is true the way text/template evaluates
+// {{if}} conditions: false, 0, a nil pointer or interface value, and an empty array, slice, map or string are false.
+func TemplateTruth(_v any) bool {
+	v := reflect.ValueOf(_v)
+	if !v.IsValid() {
+		return false
+	}
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() > 0
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() != 0
+	case reflect.Chan, reflect.Func, reflect.Pointer, reflect.Interface:
+		return !v.IsNil()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() != 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() != 0
+	}
+	return true
+}
+
+// TemplateURL returns the _url printed at the start of a url attribute, like href or src, if it's relative or
+// uses the http, https or mailto scheme. Other urls, like "javascript:" ones, are replaced by sanitize.UNSAFE_URL.
+func TemplateURL(_url string) string {
+	return sanitize.URL(_url)
+}
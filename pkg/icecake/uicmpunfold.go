@@ -3,9 +3,10 @@ package ick
 import (
 	"bytes"
 	"fmt"
+	"html"
+	"html/template"
 	"reflect"
 	"strings"
-	"text/template"
 
 	"github.com/sunraylab/icecake/pkg/errors"
 )
@@ -17,12 +18,17 @@ type TemplateData struct {
 	// Page
 }
 
+// HTML is a trusted html fragment, inserted as is by the component templates whereas any other value is escaped
+// according to its context. Never convert untrusted input to HTML, sanitize it first with the sanitize package.
+type HTML = template.HTML
+
 // unfoldComponents lookup for component tags in htmlstring, and render each of them recursively.
 //
 // rendering means:
 //  1. if the component does not have an ID yet, then create one and instantiate it
-//  2. parse component's template, according to go html templating standards
-//  3. execute this template with {{}} langage and component's data and global data
+//  2. parse component's template, according to go html templating standards, with the App template functions and partials
+//  3. execute this template with {{}} langage and component's data and global data,
+//     values are escaped according to their context like html/template does, except HTML values
 //
 // NOTICE: to avoid infinite recursivity, the rendering fails at a the 10th depth
 func unfoldComponents(_unfoldedCmps map[string]Composer, name string, _unsafeHtmlTemplate string, _data any, _deep int) (_rendered string, _err error) {
@@ -32,18 +38,82 @@ func unfoldComponents(_unfoldedCmps map[string]Composer, name string, _unsafeHtm
 	//cmpid := name + "-" + strconv.Itoa(_deep)
 	errors.ConsoleLogf("unfolding %d:%q\n", _deep, name)
 
-	// 1. parse
-	tmpCmp := template.Must(template.New(name).Parse(_unsafeHtmlTemplate))
+	// 1. parse with the App template functions and partials
+	tmpCmp, errTmp := App.templates.parse(name, _unsafeHtmlTemplate)
+	if errTmp != nil {
+		return "", errors.ConsoleErrorf("unfoldComponents stopped at level %d. %q ERROR parsing template: %s", _deep, name, errTmp.Error())
+	}
+	return unfoldTemplate(_unfoldedCmps, name, tmpCmp, _data, _deep)
+}
 
+// unfoldTemplate executes the parsed _tmpl with _data, then unfolds the components it embeds
+func unfoldTemplate(_unfoldedCmps map[string]Composer, name string, _tmpl *template.Template, _data any, _deep int) (_rendered string, _err error) {
 	// 2. execute
 	bufCmp := new(bytes.Buffer)
-	errTmp := tmpCmp.Execute(bufCmp, _data)
+	errTmp := _tmpl.Execute(bufCmp, _data)
 	if errTmp != nil {
 		return "", errors.ConsoleErrorf("unfoldComponents stopped at level %d. %q ERROR applying data to %s", _deep, name, errTmp.Error())
 	}
-	htmlstring := bufCmp.String()
 
 	// 3. lookup for components
+	return unfoldHTML(_unfoldedCmps, bufCmp.String(), _data, _deep)
+}
+
+// unfoldComponent renders the _cmp component with its compiled template if it's an HTMLRenderer,
+// or with its Template otherwise, parsed once per component type, then unfolds the components it embeds.
+//
+// A panic is recovered and returned as an error, errors are reported once with their stacktrace. An ErrorBoundary component returns its rendered fallback
+// instead of an error, without the components it embeds.
+func unfoldComponent(_unfoldedCmps map[string]Composer, name string, _cmp Composer, _data TemplateData, _deep int) (_rendered string, _err error) {
+	boundary, isBoundary := _cmp.(ErrorBoundary)
+	var unfolded map[string]bool
+	if isBoundary {
+		unfolded = make(map[string]bool, len(_unfoldedCmps))
+		for id := range _unfoldedCmps {
+			unfolded[id] = true
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_rendered, _err = "", recoverRendering(name, r)
+		} else if _err != nil {
+			_err = reportRendering(_data.Id, name, _err)
+		}
+		if _err != nil && isBoundary {
+			for id := range _unfoldedCmps {
+				if !unfolded[id] {
+					delete(_unfoldedCmps, id)
+				}
+			}
+			_rendered, _err = renderFallback(_data.Id, boundary, _err), nil
+		}
+	}()
+
+	if _deep >= 10 {
+		return "", errors.ConsoleErrorf("unfoldComponents stopped at level %d. Recursive rendering too deep", _deep)
+	}
+
+	renderer, compiled := _cmp.(HTMLRenderer)
+	if !compiled {
+		errors.ConsoleLogf("unfolding %d:%q\n", _deep, name)
+		tmpl, err := App.templates.component(_cmp)
+		if err != nil {
+			return "", errors.ConsoleErrorf("unfoldComponents stopped at level %d. %q ERROR parsing template: %s", _deep, name, err.Error())
+		}
+		return unfoldTemplate(_unfoldedCmps, name, tmpl, _data, _deep)
+	}
+	errors.ConsoleLogf("unfolding compiled %d:%q\n", _deep, name)
+
+	rendered, err := renderer.RenderHTML(_data)
+	if err != nil {
+		return "", errors.ConsoleErrorf("unfoldComponents stopped at level %d. %q ERROR rendering %s", _deep, name, err.Error())
+	}
+	return unfoldHTML(_unfoldedCmps, rendered, _data, _deep)
+}
+
+// unfoldHTML lookup for component tags in the rendered htmlstring, and render each of them recursively.
+// _data is the data the htmlstring has been rendered with, given as App to the embedded components.
+func unfoldHTML(_unfoldedCmps map[string]Composer, htmlstring string, _data any, _deep int) (_rendered string, _err error) {
 	const (
 		delim_open  = "<ick-"
 		delim_close = "/>"
@@ -89,8 +159,8 @@ nextdelim:
 				if regentry, found := App.CmpRegistry["ick-"+tagname]; found {
 
 					// Instantiate the component
-					newcmpreflect := reflect.New(regentry.typ)
-					newcmp := newcmpreflect.Interface().(Composer)
+					newcmp := regentry.newInstance()
+					newcmpreflect := reflect.ValueOf(newcmp)
 					//newcmpid := fmt.Sprintf("ick-%s-%d-%d", tagname, _deep, n)
 
 					var newcmpelem *UIComponent
@@ -112,39 +182,7 @@ nextdelim:
 						// set the id, overwrite the one in the template if any
 						attrs.SetAttribute("id", newcmpid)
 
-						anames := attrs.Sort()
-						// DEBUG:
-						//fmt.Println(anames, " => ", attrs)
-
-						for _, aname := range anames {
-							_, found := newcmpreflect.Elem().Type().FieldByName(aname)
-							if !found {
-								// DEBUG:
-								// fmt.Printf("attribute %v: %q is kept asis\n", i, aname)
-
-								// this attribute is not a field of the componenent
-								// keep it as is unless it is the class attribute, in this case, add the tokens
-								aval := attrs.GetAttribute(aname)
-								if aname == "class" {
-									newcmpelem.Classes().SetClasses(*ParseClasses(aval))
-								} else {
-									newcmpelem.SetAttribute(aname, aval)
-								}
-							} else {
-								// DEBUG:
-								// fmt.Printf("attribute %v: %q corresponds to a component's data\n", i, aname)
-
-								// feed data struct with the value
-								fieldvalue := newcmpreflect.Elem().FieldByName(aname)
-								switch fieldvalue.Kind() {
-								case reflect.String:
-									fieldvalue.SetString(attrs.GetAttribute(aname))
-								default:
-									// TODO: handle other data types
-									errors.ConsoleWarnf("unfoldComponents %q: unmanaged type for attribute %q", newcmpid, aname)
-								}
-							}
-						}
+						setComponentAttributes(newcmpid, newcmp, &newcmpelem.Element, attrs)
 
 						// recursively unfold the component template
 						data := TemplateData{
@@ -153,8 +191,9 @@ nextdelim:
 							App: _data,
 						}
 						var htmlin string
-						htmlin, _err = unfoldComponents(_unfoldedCmps, newcmpid, newcmp.Template(), data, _deep+1)
+						htmlin, _err = unfoldComponent(_unfoldedCmps, newcmpid, newcmp, data, _deep+1)
 						newcmpelem.SetInnerHTML(htmlin)
+						stampScope(&newcmpelem.Element, regentry, _unfoldedCmps)
 						htmlout := newcmpelem.OuterHTML()
 
 						// let's go deeper
@@ -172,12 +211,170 @@ nextdelim:
 	}
 }
 
+// setComponentAttributes feeds the fields of the _cmp component with the values of the _attrs of its <ick-*/> tag.
+// The other attributes are set on the _elem container of the component, the classes being added.
+func setComponentAttributes(_id string, _cmp Composer, _elem *Element, _attrs *Attributes) {
+	cmpreflect := reflect.ValueOf(_cmp)
+	for _, aname := range _attrs.Sort() {
+		_, found := cmpreflect.Elem().Type().FieldByName(aname)
+		if !found {
+			// this attribute is not a field of the componenent
+			// keep it as is unless it is the class attribute, in this case, add the tokens
+			aval := html.UnescapeString(_attrs.GetAttribute(aname))
+			if aname == "class" {
+				_elem.Classes().SetClasses(*ParseClasses(aval))
+			} else {
+				_elem.SetAttribute(aname, aval)
+			}
+			continue
+		}
+
+		// feed data struct with the value
+		fieldvalue := cmpreflect.Elem().FieldByName(aname)
+		switch {
+		case fieldvalue.Type() == reflect.TypeOf(HTML("")):
+			// the escaped template data stays escaped in a trusted html field
+			fieldvalue.SetString(_attrs.GetAttribute(aname))
+		case fieldvalue.Kind() == reflect.String:
+			fieldvalue.SetString(html.UnescapeString(_attrs.GetAttribute(aname)))
+		default:
+			if err := setFieldString(fieldvalue, html.UnescapeString(_attrs.GetAttribute(aname))); err != nil {
+				errors.ConsoleWarnf("unfoldComponents %q: attribute %q: %s", _id, aname, err)
+			}
+		}
+	}
+}
+
+// stampScope stamps the scope attribute of a scoped _entry component on the elements rendered into its _root.
+// The root elements of the components it embeds, listed in _unfoldedCmps or already mounted, are stamped
+// but not their content.
+func stampScope(_root *Element, _entry *componentRegEntry, _unfoldedCmps map[string]Composer) {
+	if _entry == nil || !_entry.scoped {
+		return
+	}
+	attr := _entry.scopeAttr()
+	var stamp func(_parent JSValue)
+	stamp = func(_parent JSValue) {
+		children := _parent.Get("children")
+		for i := 0; i < children.Length(); i++ {
+			child := children.Index(i)
+			child.Call("setAttribute", attr, "")
+			id := child.GetString("id")
+			if _, embedded := _unfoldedCmps[id]; embedded {
+				continue
+			}
+			if _, mounted := App.mounted[id]; mounted {
+				continue
+			}
+			stamp(child)
+		}
+	}
+	stamp(_root.Value())
+}
+
+// StampScope stamps the scope attribute of the closest mounted component around the _container, if its css is
+// scoped, on the elements inserted into the container after the rendering of the component, like with h.Mount.
+// The content of the embedded components is not stamped. Elements already stamped are stamped again.
+func (_app *WebApp) StampScope(_container *Element) {
+	if !_container.IsDefined() {
+		return
+	}
+	for node := _container.JSValue; node.Truthy(); node = node.Get("parentElement") {
+		if mounted, found := _app.mounted[node.GetString("id")]; found {
+			stampScope(_container, _app.LookupComponent(reflect.TypeOf(mounted.cmp)), nil)
+			return
+		}
+	}
+}
+
 // showUnfoldedComponents call addlisteners for every unfolded Components
 func showUnfoldedComponents(_unfoldedCmps map[string]Composer) {
+	showComponentsIn(GetDocument(), "", _unfoldedCmps)
+}
+
+// showComponentsIn binds the events and call addlisteners for every unfolded Components, looked up by id in the _root document or shadow root.
+// The bound listeners are released with the _owner component, or with each component if _owner is empty.
+func showComponentsIn(_root JSValueProvider, _owner string, _unfoldedCmps map[string]Composer) {
 	for id, ufc := range _unfoldedCmps {
-		e := GetDocument().ChildById(id)
+		e := CastElement(_root.Value().Call("getElementById", id))
 		ufc.Wrap(e)
+		owner := _owner
+		if owner == "" {
+			owner = id
+		}
+		App.bindElements(owner, e, ufc, _unfoldedCmps)
 		ufc.AddListeners()
 		ufc.Show()
 	}
 }
+
+// renderNewComponent creates the element of the _newcmp component and renders its template into it,
+// the element is not inserted into the DOM. Returns the components embedded in the template,
+// or the rendering error for the caller to give it to the closest error boundary where the element goes.
+func (_app *WebApp) renderNewComponent(_newcmp Composer, _appdata any) (_id string, _elem *UIComponent, _unfoldedCmps map[string]Composer, _err error) {
+	_id, _elem, _err = _app.CreateComponent(_newcmp)
+	if _err != nil {
+		return "", nil, nil, _err
+	}
+
+	// name the component
+	name := _elem.TagName() + "/" + _id
+
+	// unfold and render html for a composer
+	_unfoldedCmps = make(map[string]Composer, 0)
+	data := TemplateData{
+		Id:  _id,
+		Me:  _newcmp,
+		App: _appdata,
+	}
+	html, err := unfoldComponent(_unfoldedCmps, name, _newcmp, data, 0)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	_elem.SetInnerHTML(html)
+	stampScope(&_elem.Element, _app.LookupComponent(reflect.TypeOf(_newcmp)), _unfoldedCmps)
+	return _id, _elem, _unfoldedCmps, nil
+}
+
+// mountComponent adds the listeners of the _cmp component just inserted into the DOM and of the _unfoldedCmps
+// it embeds, shows it and tracks them.
+func (_app *WebApp) mountComponent(_id string, _elem *Element, _cmp Composer, _unfoldedCmps map[string]Composer) {
+	// addlisteners
+	showUnfoldedComponents(_unfoldedCmps)
+	_app.bindElements(_id, _elem, _cmp, _unfoldedCmps)
+	_cmp.AddListeners()
+
+	_cmp.Show()
+
+	// track the component with the ones it embeds
+	_unfoldedCmps[_id] = _cmp
+	_app.trackComponents(_unfoldedCmps)
+}
+
+// rerenderComponent renders again the template of the mounted _cmp component into its _elem. The listeners
+// of the component are removed then added again, the components embedded in the previous rendering are replaced.
+// A rendering error is given to the closest error boundary embedding the component.
+func (_app *WebApp) rerenderComponent(_id string, _elem *Element, _cmp Composer, _appdata any) {
+	_app.releaseBindings(_id)
+	if listened, ok := _cmp.(interface{ RemoveListeners() }); ok {
+		listened.RemoveListeners()
+	}
+
+	unfoldedCmps := make(map[string]Composer, 0)
+	data := TemplateData{
+		Id:  _id,
+		Me:  _cmp,
+		App: _appdata,
+	}
+	html, err := unfoldComponent(unfoldedCmps, _elem.TagName()+"/"+_id, _cmp, data, 0)
+	if err != nil && _app.catchBoundary(_elem.Get("parentElement"), err) {
+		return
+	}
+	_elem.SetInnerHTML(html)
+	stampScope(_elem, _app.LookupComponent(reflect.TypeOf(_cmp)), unfoldedCmps)
+
+	showUnfoldedComponents(unfoldedCmps)
+	_app.bindElements(_id, _elem, _cmp, unfoldedCmps)
+	_cmp.AddListeners()
+	_app.trackComponents(unfoldedCmps)
+}
@@ -0,0 +1,471 @@
+package ick
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"syscall/js"
+	"time"
+
+	"github.com/sunraylab/icecake/pkg/errors"
+)
+
+/****************************************************************************
+* enum used by WebSocket
+*****************************************************************************/
+
+type WS_READYSTATE int
+
+const (
+	WS_CONNECTING WS_READYSTATE = 0 // Socket has been created. The connection is not yet open.
+	WS_OPEN       WS_READYSTATE = 1 // The connection is open and ready to communicate.
+	WS_CLOSING    WS_READYSTATE = 2 // The connection is in the process of closing.
+	WS_CLOSED     WS_READYSTATE = 3 // The connection is closed or couldn't be opened.
+)
+
+func (_state WS_READYSTATE) String() string {
+	switch _state {
+	case WS_CONNECTING:
+		return "connecting"
+	case WS_OPEN:
+		return "open"
+	case WS_CLOSING:
+		return "closing"
+	}
+	return "closed"
+}
+
+// types of WSMessage envelopes, must match the ones of the spaserver WSHub
+const (
+	WSMSG_SUBSCRIBE   string = "subscribe"   // client -> hub: start receiving messages published on Topic
+	WSMSG_UNSUBSCRIBE string = "unsubscribe" // client -> hub: stop receiving messages published on Topic
+	WSMSG_PUBLISH     string = "publish"     // client -> hub: publish Data on Topic
+	WSMSG_MESSAGE     string = "message"     // hub -> client: Data has been published on Topic
+	WSMSG_ERROR       string = "error"       // hub -> client: the last client request failed, Data holds the reason
+)
+
+/****************************************************************************
+* WSMessage
+*****************************************************************************/
+
+// WSMessage is the JSON envelope of every message exchanged with the spaserver websocket hub.
+type WSMessage struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// Decode unmarshals the json data of the message into _v
+func (_msg WSMessage) Decode(_v any) error {
+	return json.Unmarshal(_msg.Data, _v)
+}
+
+/****************************************************************************
+* WebSocket
+*****************************************************************************/
+
+// WebSocket wraps the browser WebSocket API to exchange WSMessages with the spaserver websocket hub.
+//
+// Received messages are delivered to the OnMessage callback, and to topic subscribers either with a go channel or a callback.
+// When the connection drops, the WebSocket reconnects automatically with an exponential backoff,
+// and restores its topic subscriptions. Reconnection is suspended while the browser is offline
+// and is triggered as soon as the browser comes back online.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/WebSocket
+type WebSocket struct {
+	OnMessage func(_msg *WSMessage) // Optional function called for every message received, before topic subscribers
+	OnOpen    func(_ws *WebSocket)  // Optional function called in its own go routine every time the connection opens, including reconnections
+	OnClose   func(_ws *WebSocket)  // Optional function called in its own go routine every time the connection closes
+
+	MinBackoff time.Duration // delay before the first reconnection attempt, 500ms by default
+	MaxBackoff time.Duration // maximum delay between two reconnection attempts, 30s by default
+
+	url    string
+	window Window // the window listening online/offline events
+
+	mu          sync.Mutex
+	jsws        *EventTarget // the current js WebSocket, nil if not connected
+	closed      bool         // true once Close has been called, stops reconnection
+	backoff     time.Duration
+	retry       *time.Timer
+	subscribers map[string][]*wsSubscriber
+	inbox       chan WSMessage
+}
+
+type wsSubscriber struct {
+	ch       chan WSMessage
+	callback func(_msg *WSMessage)
+}
+
+// NewWebSocket is the WebSocket factory. The connection is not opened until Open is called.
+//
+// _url can be relative to the current page location, ie. "/ws", in this case the ws or wss scheme is
+// choosen according to the page scheme.
+func NewWebSocket(_url string) *WebSocket {
+	ws := new(WebSocket)
+	ws.url = wsResolveURL(_url)
+	ws.MinBackoff = 500 * time.Millisecond
+	ws.MaxBackoff = 30 * time.Second
+	ws.subscribers = make(map[string][]*wsSubscriber)
+	ws.inbox = make(chan WSMessage, 64)
+	ws.closed = true
+	return ws
+}
+
+// URL returns the absolute URL of the websocket endpoint.
+func (_ws *WebSocket) URL() string {
+	return _ws.url
+}
+
+// ReadyState returns the current state of the connection.
+//
+// https://developer.mozilla.org/en-US/docs/Web/API/WebSocket/readyState
+func (_ws *WebSocket) ReadyState() WS_READYSTATE {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	return _ws.readyState()
+}
+
+func (_ws *WebSocket) readyState() WS_READYSTATE {
+	if _ws.jsws == nil {
+		return WS_CLOSED
+	}
+	return WS_READYSTATE(_ws.jsws.GetInt("readyState"))
+}
+
+// Open opens the connection and starts listening to browser online/offline events.
+// Does nothing if the WebSocket is already opened.
+func (_ws *WebSocket) Open() {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	if !_ws.closed {
+		return
+	}
+	_ws.closed = false
+	_ws.backoff = _ws.MinBackoff
+
+	_ws.window = GetWindow()
+	_ws.window.AddListener(&eventHandler{eventtype: string(GENERIC_WIN_ONLINE), jsHandler: makeWindow_Generic_Event(_ws.onOnline)})
+	_ws.window.AddListener(&eventHandler{eventtype: string(GENERIC_WIN_OFFLINE), jsHandler: makeWindow_Generic_Event(_ws.onOffline)})
+
+	go _ws.dispatch(_ws.inbox)
+
+	_ws.connect()
+}
+
+// Close closes the connection, stops reconnection attempts and closes every subscriber channel.
+func (_ws *WebSocket) Close() {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	if _ws.closed {
+		return
+	}
+	_ws.closed = true
+	if _ws.retry != nil {
+		_ws.retry.Stop()
+		_ws.retry = nil
+	}
+	_ws.window.RemoveListeners()
+	_ws.disconnect()
+
+	for topic, subs := range _ws.subscribers {
+		for _, sub := range subs {
+			if sub.ch != nil {
+				close(sub.ch)
+			}
+		}
+		delete(_ws.subscribers, topic)
+	}
+	close(_ws.inbox)
+	_ws.inbox = make(chan WSMessage, 64)
+}
+
+// Subscribe subscribes to _topic and returns a channel receiving every message published on it.
+// The channel is closed by Unsubscribe or Close.
+//
+// Messages are dropped if the channel buffer is full, so the channel must be read continuously.
+func (_ws *WebSocket) Subscribe(_topic string) <-chan WSMessage {
+	sub := &wsSubscriber{ch: make(chan WSMessage, 16)}
+	_ws.addSubscriber(_topic, sub)
+	return sub.ch
+}
+
+// OnTopic subscribes to _topic and calls _callback for every message published on it.
+func (_ws *WebSocket) OnTopic(_topic string, _callback func(_msg *WSMessage)) {
+	_ws.addSubscriber(_topic, &wsSubscriber{callback: _callback})
+}
+
+// Unsubscribe removes every subscriber to _topic and closes their channels.
+func (_ws *WebSocket) Unsubscribe(_topic string) {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	subs, found := _ws.subscribers[_topic]
+	if !found {
+		return
+	}
+	for _, sub := range subs {
+		if sub.ch != nil {
+			close(sub.ch)
+		}
+	}
+	delete(_ws.subscribers, _topic)
+	_ws.send(WSMessage{Type: WSMSG_UNSUBSCRIBE, Topic: _topic})
+}
+
+// Publish sends _data, encoded in json, to the hub on _topic.
+// Returns an error if the connection is not open.
+func (_ws *WebSocket) Publish(_topic string, _data any) error {
+	data, err := json.Marshal(_data)
+	if err != nil {
+		return errors.ConsoleErrorf("WebSocket publish %q failed: %s", _topic, err)
+	}
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	return _ws.send(WSMessage{Type: WSMSG_PUBLISH, Topic: _topic, Data: data})
+}
+
+func (_ws *WebSocket) addSubscriber(_topic string, _sub *wsSubscriber) {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	subs := _ws.subscribers[_topic]
+	_ws.subscribers[_topic] = append(subs, _sub)
+	if len(subs) == 0 && _ws.readyState() == WS_OPEN {
+		_ws.send(WSMessage{Type: WSMSG_SUBSCRIBE, Topic: _topic})
+	}
+}
+
+// send sends the message if the connection is open. Must be called with the mutex locked.
+func (_ws *WebSocket) send(_msg WSMessage) error {
+	if _ws.readyState() != WS_OPEN {
+		return fmt.Errorf("WebSocket send %q failed: connection is %s", _msg.Type, _ws.readyState())
+	}
+	payload, err := json.Marshal(_msg)
+	if err != nil {
+		return fmt.Errorf("WebSocket send %q failed: %w", _msg.Type, err)
+	}
+	_ws.jsws.Call("send", string(payload))
+	return nil
+}
+
+// connect creates a new js WebSocket. Must be called with the mutex locked.
+func (_ws *WebSocket) connect() {
+	if _ws.closed || _ws.jsws != nil {
+		return
+	}
+	if !_ws.window.OnLine() {
+		errors.ConsoleLogf("WebSocket %s: browser is offline, waiting to be online\n", _ws.url)
+		return
+	}
+
+	jsv, err := wsNew(_ws.url)
+	if err != nil {
+		errors.ConsoleErrorf("WebSocket %s: %s", _ws.url, err)
+		return
+	}
+	_ws.jsws = CastEventTarget(jsv)
+	_ws.jsws.AddListener(&eventHandler{eventtype: "open", jsHandler: makeWebSocket_Event(_ws.onOpen)})
+	_ws.jsws.AddListener(&eventHandler{eventtype: "message", jsHandler: makeWebSocket_Event(_ws.onMessage)})
+	_ws.jsws.AddListener(&eventHandler{eventtype: "close", jsHandler: makeWebSocket_Event(_ws.onClose)})
+	_ws.jsws.AddListener(&eventHandler{eventtype: "error", jsHandler: makeWebSocket_Event(_ws.onError)})
+}
+
+// disconnect releases and closes the current js WebSocket. Must be called with the mutex locked.
+func (_ws *WebSocket) disconnect() {
+	if _ws.jsws == nil {
+		return
+	}
+	jsws := _ws.jsws
+	_ws.jsws = nil
+	jsws.RemoveListeners()
+	if state := WS_READYSTATE(jsws.GetInt("readyState")); state == WS_CONNECTING || state == WS_OPEN {
+		jsws.Call("close")
+	}
+}
+
+// reconnect schedules a new connection after the current backoff delay,
+// then doubles the backoff up to MaxBackoff. Must be called with the mutex locked.
+func (_ws *WebSocket) reconnect() {
+	if _ws.closed || _ws.retry != nil {
+		return
+	}
+	delay := _ws.backoff
+	_ws.backoff *= 2
+	if _ws.backoff > _ws.MaxBackoff {
+		_ws.backoff = _ws.MaxBackoff
+	}
+	errors.ConsoleLogf("WebSocket %s: reconnecting in %v\n", _ws.url, delay)
+	_ws.retry = time.AfterFunc(delay, func() {
+		_ws.mu.Lock()
+		defer _ws.mu.Unlock()
+		_ws.retry = nil
+		_ws.connect()
+	})
+}
+
+// dispatch delivers every message received in the inbox to the OnMessage callback and to topic subscribers.
+// It runs in its own go routine until the inbox is closed.
+func (_ws *WebSocket) dispatch(_inbox chan WSMessage) {
+	for msg := range _inbox {
+		msg := msg
+		if _ws.OnMessage != nil {
+			_ws.OnMessage(&msg)
+		}
+
+		_ws.mu.Lock()
+		subs := append([]*wsSubscriber(nil), _ws.subscribers[msg.Topic]...)
+		for _, sub := range subs {
+			if sub.ch == nil {
+				continue
+			}
+			select {
+			case sub.ch <- msg:
+			default:
+				errors.ConsoleWarnf("WebSocket %s: subscriber channel full, message on %q dropped", _ws.url, msg.Topic)
+			}
+		}
+		_ws.mu.Unlock()
+
+		for _, sub := range subs {
+			if sub.callback != nil {
+				sub.callback(&msg)
+			}
+		}
+	}
+}
+
+/****************************************************************************
+* WebSocket's events
+*****************************************************************************/
+
+func (_ws *WebSocket) onOpen(_event *Event) {
+	_ws.mu.Lock()
+	_ws.backoff = _ws.MinBackoff
+	for topic := range _ws.subscribers {
+		_ws.send(WSMessage{Type: WSMSG_SUBSCRIBE, Topic: topic})
+	}
+	_ws.mu.Unlock()
+
+	errors.ConsoleLogf("WebSocket %s: open\n", _ws.url)
+	if _ws.OnOpen != nil {
+		go _ws.OnOpen(_ws)
+	}
+}
+
+func (_ws *WebSocket) onMessage(_event *Event) {
+	data := _event.Get("data")
+	if data.Type() != TYPE_STRING {
+		errors.ConsoleWarnf("WebSocket %s: binary message ignored", _ws.url)
+		return
+	}
+	var msg WSMessage
+	if err := json.Unmarshal([]byte(data.String()), &msg); err != nil {
+		errors.ConsoleWarnf("WebSocket %s: unable to decode message: %s", _ws.url, err)
+		return
+	}
+	if msg.Type == WSMSG_ERROR {
+		errors.ConsoleWarnf("WebSocket %s: hub error on %q: %s", _ws.url, msg.Topic, string(msg.Data))
+	}
+
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	if _ws.closed {
+		return
+	}
+	select {
+	case _ws.inbox <- msg:
+	default:
+		errors.ConsoleWarnf("WebSocket %s: inbox full, message on %q dropped", _ws.url, msg.Topic)
+	}
+}
+
+func (_ws *WebSocket) onClose(_event *Event) {
+	_ws.mu.Lock()
+	_ws.disconnect()
+	_ws.reconnect()
+	_ws.mu.Unlock()
+
+	errors.ConsoleLogf("WebSocket %s: closed, code %v\n", _ws.url, _event.GetInt("code"))
+	if _ws.OnClose != nil {
+		go _ws.OnClose(_ws)
+	}
+}
+
+func (_ws *WebSocket) onError(_event *Event) {
+	// a close event always follows an error event, so reconnection is handled by onClose
+	errors.ConsoleWarnf("WebSocket %s: connection error", _ws.url)
+}
+
+// onOnline reconnects immediately when the browser comes back online
+func (_ws *WebSocket) onOnline(_event *Event, _win *Window) {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	if _ws.retry != nil {
+		_ws.retry.Stop()
+		_ws.retry = nil
+	}
+	_ws.backoff = _ws.MinBackoff
+	_ws.connect()
+}
+
+// onOffline drops the connection which can't work anymore, the browser online event will trigger the reconnection
+func (_ws *WebSocket) onOffline(_event *Event, _win *Window) {
+	_ws.mu.Lock()
+	defer _ws.mu.Unlock()
+	if _ws.retry != nil {
+		_ws.retry.Stop()
+		_ws.retry = nil
+	}
+	_ws.disconnect()
+}
+
+// event attribute: Event
+func makeWebSocket_Event(listener func(event *Event)) js.Func {
+	fn := func(this js.Value, args []js.Value) interface{} {
+		value := val(args[0])
+		evt := CastEvent(value)
+		defer App.scheduleRefresh()
+		defer func() {
+			if r := recover(); r != nil {
+				errors.ConsoleStackf(r, "Error occurs processing event %q on WebSocket", evt.Type())
+			}
+		}()
+		listener(evt)
+		return js.Undefined()
+	}
+	return js.FuncOf(fn)
+}
+
+/****************************************************************************
+* helpers
+*****************************************************************************/
+
+// wsNew creates a new js WebSocket, catching the js exception thrown with an invalid url.
+func wsNew(_url string) (_jsv JSValue, _err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			_err = fmt.Errorf("unable to create the websocket: %v", r)
+		}
+	}()
+	return val(js.Global().Get("WebSocket").New(_url)), nil
+}
+
+// wsResolveURL returns an absolute ws:// or wss:// url, resolving _url relatively to the current page location.
+func wsResolveURL(_url string) string {
+	u, err := url.Parse(_url)
+	if err != nil {
+		errors.ConsoleWarnf("WebSocket url %q: %s", _url, err)
+		return _url
+	}
+	if u.Scheme == "ws" || u.Scheme == "wss" {
+		return u.String()
+	}
+	abs := GetWindow().URL().ResolveReference(u)
+	if abs.Scheme == "https" {
+		abs.Scheme = "wss"
+	} else {
+		abs.Scheme = "ws"
+	}
+	abs.Fragment = ""
+	return abs.String()
+}
@@ -0,0 +1,337 @@
+// Package sanitize cleans untrusted html with an allow-list policy, before inserting it into the DOM:
+//
+//	elem.SetInnerHTML(sanitize.HTML(userInput))
+//
+// Only the elements and attributes allowed by the Policy are kept, with their values escaped.
+// Elements not allowed are removed but their text is kept, except for scripts, styles and embedded
+// contents which are removed with their content. Comments are removed.
+//
+// The package does not depend on the DOM, so it's used both by the wasm app and by the server.
+package sanitize
+
+import (
+	"html"
+	"strings"
+)
+
+/******************************************************************************
+* Policy
+******************************************************************************/
+
+// Policy is an allow-list of html elements and attributes
+type Policy struct {
+	Elements   map[string][]string // allowed elements, with the attributes allowed on each of them
+	Attributes []string            // attributes allowed on every allowed element
+	URLSchemes []string            // schemes allowed in url attributes like href or src. Relative urls are always allowed.
+}
+
+// DefaultPolicy returns a new policy allowing text formatting, headings, links, images, lists and tables,
+// with http, https and mailto urls. Style and event attributes are not allowed.
+func DefaultPolicy() *Policy {
+	policy := &Policy{
+		Elements:   make(map[string][]string),
+		Attributes: []string{"class", "title", "lang", "dir"},
+		URLSchemes: []string{"http", "https", "mailto"},
+	}
+	policy.AllowElements("abbr", "b", "br", "code", "dd", "del", "div", "dl", "dt", "em", "figcaption", "figure",
+		"h1", "h2", "h3", "h4", "h5", "h6", "hr", "i", "ins", "kbd", "li", "mark", "p", "pre", "s", "small",
+		"span", "strong", "sub", "sup", "table", "tbody", "tfoot", "thead", "tr", "u", "ul")
+	policy.AllowAttributes("a", "href", "rel", "target")
+	policy.AllowAttributes("blockquote", "cite")
+	policy.AllowAttributes("q", "cite")
+	policy.AllowAttributes("img", "src", "alt", "width", "height")
+	policy.AllowAttributes("ol", "start")
+	policy.AllowAttributes("td", "colspan", "rowspan", "align")
+	policy.AllowAttributes("th", "colspan", "rowspan", "align", "scope")
+	return policy
+}
+
+// AllowElements adds the _names elements to the allow-list, without specific attributes
+func (_policy *Policy) AllowElements(_names ...string) *Policy {
+	for _, name := range _names {
+		name = strings.ToLower(name)
+		if _, found := _policy.Elements[name]; !found {
+			_policy.Elements[name] = []string{}
+		}
+	}
+	return _policy
+}
+
+// AllowAttributes adds the _elem element to the allow-list with the _attrs attributes
+func (_policy *Policy) AllowAttributes(_elem string, _attrs ...string) *Policy {
+	_elem = strings.ToLower(_elem)
+	for _, attr := range _attrs {
+		_policy.Elements[_elem] = append(_policy.Elements[_elem], strings.ToLower(attr))
+	}
+	if _, found := _policy.Elements[_elem]; !found {
+		_policy.Elements[_elem] = []string{}
+	}
+	return _policy
+}
+
+// allowedAttr reports whether the _attr attribute is allowed on the _elem element
+func (_policy *Policy) allowedAttr(_elem string, _attr string) bool {
+	for _, attr := range _policy.Attributes {
+		if attr == _attr {
+			return true
+		}
+	}
+	for _, attr := range _policy.Elements[_elem] {
+		if attr == _attr {
+			return true
+		}
+	}
+	return false
+}
+
+// url attributes, their values are checked against the allowed schemes
+var urlAttributes = map[string]bool{
+	"href": true, "src": true, "cite": true, "action": true, "formaction": true, "poster": true, "background": true,
+}
+
+// allowedURL reports whether the _url is relative or uses an allowed scheme
+func (_policy *Policy) allowedURL(_url string) bool {
+	// browsers ignore control chars and spaces in urls, ie. "java\tscript:"
+	url := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, _url)
+	colon := strings.IndexByte(url, ':')
+	if colon == -1 || strings.ContainsAny(url[:colon], "/?#") {
+		return true
+	}
+	scheme := strings.ToLower(url[:colon])
+	for _, allowed := range _policy.URLSchemes {
+		if scheme == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// UNSAFE_URL replaces the urls rejected by URL, it does not link anywhere
+const UNSAFE_URL = "about:invalid#unsafe-url"
+
+// URL returns the _url if it's relative or uses a scheme allowed by the DefaultPolicy, otherwise UNSAFE_URL.
+// The returned url is not escaped.
+func URL(_url string) string {
+	if DefaultPolicy().allowedURL(_url) {
+		return _url
+	}
+	return UNSAFE_URL
+}
+
+/******************************************************************************
+* Sanitizer
+******************************************************************************/
+
+// elements removed with their content
+var removedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "object": true, "embed": true, "noscript": true, "noembed": true,
+	"noframes": true, "template": true, "textarea": true, "title": true, "xmp": true, "svg": true, "math": true,
+}
+
+// void elements never have children nor a closing tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// HTML sanitizes the _unsafeHtml with the DefaultPolicy
+func HTML(_unsafeHtml string) string {
+	return DefaultPolicy().Sanitize(_unsafeHtml)
+}
+
+// Sanitize returns the _unsafeHtml with only the elements and the attributes allowed by the policy.
+// Texts and attribute values are escaped, and elements left open are closed.
+func (_policy *Policy) Sanitize(_unsafeHtml string) string {
+	var out strings.Builder
+	open := make([]string, 0) // the stack of the open elements
+	src := _unsafeHtml
+	for len(src) > 0 {
+		lt := strings.IndexByte(src, '<')
+		if lt == -1 {
+			writeText(&out, src)
+			break
+		}
+		writeText(&out, src[:lt])
+		src = src[lt:]
+
+		switch {
+		case strings.HasPrefix(src, "<!--"):
+			src = skipAfter(src[4:], "-->")
+
+		case strings.HasPrefix(src, "<!") || strings.HasPrefix(src, "<?"):
+			src = skipAfter(src[2:], ">")
+
+		case strings.HasPrefix(src, "</") && len(src) > 2 && isLetter(src[2]):
+			var name string
+			name, src = readName(src[2:])
+			src = skipAfter(src, ">")
+			if _, allowed := _policy.Elements[name]; !allowed {
+				continue
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == name {
+					for j := len(open) - 1; j >= i; j-- {
+						out.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+
+		case len(src) > 1 && isLetter(src[1]):
+			var name string
+			var attrs []attribute
+			var complete bool
+			name, src = readName(src[1:])
+			attrs, src, complete = readAttributes(src)
+			if !complete {
+				// the tag never ends, browsers ignore it
+				break
+			}
+			if removedElements[name] {
+				src = skipRawText(src, name)
+				continue
+			}
+			if _, allowed := _policy.Elements[name]; !allowed {
+				continue
+			}
+			out.WriteString("<" + name)
+			for _, attr := range attrs {
+				if !_policy.allowedAttr(name, attr.name) || (urlAttributes[attr.name] && !_policy.allowedURL(attr.value)) {
+					continue
+				}
+				out.WriteString(" " + attr.name + `="` + html.EscapeString(attr.value) + `"`)
+			}
+			out.WriteString(">")
+			if !voidElements[name] {
+				open = append(open, name)
+			}
+
+		default:
+			// a lonely '<'
+			out.WriteString("&lt;")
+			src = src[1:]
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		out.WriteString("</" + open[i] + ">")
+	}
+	return out.String()
+}
+
+/******************************************************************************
+* Tokenizer
+******************************************************************************/
+
+type attribute struct {
+	name  string
+	value string
+}
+
+// writeText writes the _text escaped, entities are decoded first to not escape them twice
+func writeText(_out *strings.Builder, _text string) {
+	_out.WriteString(html.EscapeString(html.UnescapeString(_text)))
+}
+
+func isLetter(_c byte) bool {
+	return (_c >= 'a' && _c <= 'z') || (_c >= 'A' && _c <= 'Z')
+}
+
+func isSpace(_c byte) bool {
+	return _c == ' ' || _c == '\t' || _c == '\n' || _c == '\r' || _c == '\f'
+}
+
+// skipAfter returns _src after the first _delim, or an empty string if _delim is not found
+func skipAfter(_src string, _delim string) string {
+	if i := strings.Index(_src, _delim); i != -1 {
+		return _src[i+len(_delim):]
+	}
+	return ""
+}
+
+// skipRawText returns _src after the closing tag of the _name element
+func skipRawText(_src string, _name string) string {
+	lower := strings.ToLower(_src)
+	for from := 0; ; {
+		i := strings.Index(lower[from:], "</"+_name)
+		if i == -1 {
+			return ""
+		}
+		end := from + i + 2 + len(_name)
+		if end == len(lower) || lower[end] == '>' || lower[end] == '/' || isSpace(lower[end]) {
+			return skipAfter(_src[end:], ">")
+		}
+		from = end
+	}
+}
+
+// readName reads a tag or an attribute name, returned lower case
+func readName(_src string) (_name string, _left string) {
+	i := 0
+	for i < len(_src) && !isSpace(_src[i]) && _src[i] != '>' && _src[i] != '/' && _src[i] != '=' {
+		i++
+	}
+	return strings.ToLower(_src[:i]), _src[i:]
+}
+
+// readAttributes reads the attributes of a start tag up to its end, entities of the values are decoded.
+// _complete is false if the end of the tag is missing.
+func readAttributes(_src string) (_attrs []attribute, _left string, _complete bool) {
+	for {
+		for len(_src) > 0 && (isSpace(_src[0]) || _src[0] == '/') {
+			_src = _src[1:]
+		}
+		if len(_src) == 0 {
+			return _attrs, "", false
+		}
+		if _src[0] == '>' {
+			return _attrs, _src[1:], true
+		}
+
+		var attr attribute
+		attr.name, _src = readName(_src)
+		if attr.name == "" {
+			// a lonely '=', skip it
+			_src = _src[1:]
+			continue
+		}
+		rest := strings.TrimLeft(_src, " \t\n\r\f")
+		if strings.HasPrefix(rest, "=") {
+			rest = strings.TrimLeft(rest[1:], " \t\n\r\f")
+			var value string
+			if len(rest) > 0 && (rest[0] == '"' || rest[0] == '\'') {
+				end := strings.IndexByte(rest[1:], rest[0])
+				if end == -1 {
+					return _attrs, "", false
+				}
+				value, _src = rest[1:end+1], rest[end+2:]
+			} else {
+				i := 0
+				for i < len(rest) && !isSpace(rest[i]) && rest[i] != '>' {
+					i++
+				}
+				value, _src = rest[:i], rest[i:]
+			}
+			attr.value = html.UnescapeString(value)
+		}
+		if isValidAttrName(attr.name) {
+			_attrs = append(_attrs, attr)
+		}
+	}
+}
+
+// isValidAttrName reports whether the _name is made of letters, digits, '-', '_' or ':' only
+func isValidAttrName(_name string) bool {
+	for i := 0; i < len(_name); i++ {
+		c := _name[i]
+		if !isLetter(c) && !(c >= '0' && c <= '9') && c != '-' && c != '_' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
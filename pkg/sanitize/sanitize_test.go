@@ -0,0 +1,53 @@
+package sanitize
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		src      string
+		expected string
+	}{
+		{src: `hello <b>world</b>`, expected: `hello <b>world</b>`},
+		{src: `<p onclick="alert(1)" class="x">a &amp; b < c</p>`, expected: `<p class="x">a &amp; b &lt; c</p>`},
+		{src: `<script>alert("x")</script>ok`, expected: `ok`},
+		{src: `<STYLE type="text/css">p{}</style ><i>i</I>`, expected: `<i>i</i>`},
+		{src: `<img src=x onerror=alert(1)>`, expected: `<img src="x">`},
+		{src: `<a href="javascript:alert(1)">x</a>`, expected: `<a>x</a>`},
+		{src: `<a href="java&#x09;script:alert(1)">x</a>`, expected: `<a>x</a>`},
+		{src: `<a href='https://example.com/?a=1&amp;b="2"' target=_blank>x</a>`, expected: `<a href="https://example.com/?a=1&amp;b=&#34;2&#34;" target="_blank">x</a>`},
+		{src: `<a href="/doc:1">x</a><a href="mailto:me@example.com">y</a>`, expected: `<a href="/doc:1">x</a><a href="mailto:me@example.com">y</a>`},
+		{src: `<div><custom-tag>text</custom-tag><!-- <b>comment</b> --></div>`, expected: `<div>text</div>`},
+		{src: `<ul><li>one<li>two</ul></div>`, expected: `<ul><li>one<li>two</li></li></ul>`},
+		{src: `<strong>open <em>nested`, expected: `<strong>open <em>nested</em></strong>`},
+		{src: `<img src="x.png" alt="a > b"/><br/>`, expected: `<img src="x.png" alt="a &gt; b"><br>`},
+		{src: `<iframe src="https://evil.com">fallback</iframe><p title="t`, expected: ``},
+	}
+	for i, tst := range tests {
+		if got := HTML(tst.src); got != tst.expected {
+			t.Errorf("test %d: %q expected, got %q", i, tst.expected, got)
+		}
+	}
+
+	policy := DefaultPolicy().AllowAttributes("span", "style")
+	policy.URLSchemes = []string{"https"}
+	if got := policy.Sanitize(`<span style="color: red">x</span><a href="http://a.com">y</a>`); got != `<span style="color: red">x</span><a>y</a>` {
+		t.Errorf("unexpected custom policy result %q", got)
+	}
+}
+
+func TestURL(t *testing.T) {
+	for url, expected := range map[string]string{
+		"https://example.com/a?b=c": "https://example.com/a?b=c",
+		"/users/1":                  "/users/1",
+		"page.html#a:b":             "page.html#a:b",
+		"mailto:me@example.com":     "mailto:me@example.com",
+		"javascript:alert(1)":       UNSAFE_URL,
+		" JavaScript:alert(1)":      UNSAFE_URL,
+		"java\tscript:alert(1)":     UNSAFE_URL,
+		"data:text/html,<b>x</b>":   UNSAFE_URL,
+	} {
+		if got := URL(url); got != expected {
+			t.Errorf("%q: %q expected, got %q", url, expected, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// APPCONFIG_ID is the id of the script element holding the json config of the wasm app
+const APPCONFIG_ID = "ick-config"
+
+// LoadAppConfig returns the values of the env variables starting with _prefix, keyed by their name without the prefix.
+// Only those variables are exposed to the wasm app, so the prefix must not match any secret.
+// Returns an empty map if _prefix is empty.
+func LoadAppConfig(_prefix string) map[string]any {
+	config := make(map[string]any)
+	if _prefix == "" {
+		return config
+	}
+	for _, env := range os.Environ() {
+		key, value, _ := strings.Cut(env, "=")
+		if name := strings.TrimPrefix(key, _prefix); name != key && name != "" {
+			config[name] = value
+		}
+	}
+	return config
+}
+
+// rexpConfigScript matches the config script element of a page, with its content
+var rexpConfigScript = regexp.MustCompile(`(?is)(<script\b[^>]*\bid\s*=\s*["']` + APPCONFIG_ID + `["'][^>]*>)(.*?)(</script\s*>)`)
+
+// rexpHeadEnd matches the end of the head of a page
+var rexpHeadEnd = regexp.MustCompile(`(?i)</head\s*>`)
+
+// injectAppConfig returns the _html page with the json _config as the content of its config script element:
+//
+//	<script id="ick-config" type="application/json"></script>
+//
+// Without such an element, one is inserted before </head> if the _config is not empty.
+// The rest of the page is left as is, it's not a template.
+func injectAppConfig(_html []byte, _config map[string]any) []byte {
+	if _config == nil {
+		_config = make(map[string]any)
+	}
+	// json escapes <, > and &, so the config can't close the script element
+	data, err := json.Marshal(_config)
+	if err != nil {
+		log.Printf("spa server: encoding the app config failed: %s", err)
+		return _html
+	}
+
+	var out bytes.Buffer
+	if loc := rexpConfigScript.FindSubmatchIndex(_html); loc != nil {
+		out.Write(_html[:loc[3]])
+		out.Write(data)
+		out.Write(_html[loc[6]:])
+		return out.Bytes()
+	}
+	loc := rexpHeadEnd.FindIndex(_html)
+	if len(_config) == 0 || loc == nil {
+		return _html
+	}
+	out.Write(_html[:loc[0]])
+	fmt.Fprintf(&out, `<script id="%s" type="application/json">%s</script>`, APPCONFIG_ID, data)
+	out.Write(_html[loc[0]:])
+	return out.Bytes()
+}
@@ -0,0 +1,280 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	stdhtml "html"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sunraylab/icecake/pkg/extensions/markdown"
+	"github.com/sunraylab/icecake/pkg/h"
+	"github.com/yuin/goldmark"
+	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// ExportRoute is a page of the static site export.
+type ExportRoute struct {
+	Path      string         `json:"path"`                // the route path, "/docs/" is exported to "docs/index.html"
+	Page      string         `json:"page,omitempty"`      // the html page within the static files, "index.html" by default
+	Markdown  string         `json:"markdown,omitempty"`  // the optional markdown file rendered into the page, relative to the routes file
+	Target    string         `json:"target,omitempty"`    // the id of the page element receiving the rendered markdown
+	Data      map[string]any `json:"data,omitempty"`      // the data of the markdown template, like the _data of markdown.RenderMarkdown
+	Unsafe    bool           `json:"unsafe,omitempty"`    // renders raw html and components embedded into the markdown
+	Highlight bool           `json:"highlight,omitempty"` // highlights code blocks
+}
+
+// LoadExportRoutes loads the json array of routes of the _filename file.
+func LoadExportRoutes(_filename string) ([]ExportRoute, error) {
+	data, err := os.ReadFile(_filename)
+	if err != nil {
+		return nil, err
+	}
+	routes := make([]ExportRoute, 0)
+	if err := json.Unmarshal(data, &routes); err != nil {
+		return nil, fmt.Errorf("routes file %q: %w", _filename, err)
+	}
+	for i, route := range routes {
+		if route.Markdown != "" && !filepath.IsAbs(route.Markdown) {
+			routes[i].Markdown = filepath.Join(filepath.Dir(_filename), route.Markdown)
+		}
+	}
+	return routes, nil
+}
+
+// file returns the name of the exported file of the route, relative to the export directory
+func (_route ExportRoute) file() (string, error) {
+	if !strings.HasPrefix(_route.Path, "/") {
+		return "", fmt.Errorf("route %q must start with /", _route.Path)
+	}
+	name := _route.Path
+	if strings.HasSuffix(name, "/") {
+		name += "index.html"
+	} else if path.Ext(name) != ".html" {
+		return "", fmt.Errorf("route %q must end with / or .html", _route.Path)
+	}
+	return strings.TrimPrefix(path.Clean(name), "/"), nil
+}
+
+// Export writes a static site into the _outdir directory, deployable to any static host:
+//  1. every static file is copied, index.html pages being rendered with the AppConfig and their components
+//  2. every route page is rendered with the AppConfig, its components and its markdown, as the wasm app would do
+//     with markdown.RenderMarkdown
+//
+// The <ick-*/> tags of the pages and of the markdown are rendered with the ExportComponents, into containers marked
+// with a data-ick-prerendered attribute, the wasm app hydrates them instead of rendering them again.
+// The target element gets a data-ick-prerendered attribute too, unless its markdown embeds components without a renderer:
+// markdown.RenderMarkdown then renders it again.
+func (ws WebServer) Export(_routes []ExportRoute, _outdir string) error {
+	fsys := ws.StaticFS
+	if fsys == nil {
+		fsys = os.DirFS(ws.staticfiledir)
+	}
+
+	// 1. copy the static files
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		if path.Base(name) == "index.html" {
+			data = injectAppConfig(data, ws.AppConfig)
+			if data, _, err = ws.newExportRenderer().render(data, 0); err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+		}
+		return writeExportFile(_outdir, name, data)
+	})
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	// 2. render the routes
+	for _, route := range _routes {
+		name, err := route.file()
+		if err != nil {
+			return fmt.Errorf("export failed: %w", err)
+		}
+		page, err := ws.exportPage(fsys, route)
+		if err != nil {
+			return fmt.Errorf("export %q failed: %w", route.Path, err)
+		}
+		if err := writeExportFile(_outdir, name, page); err != nil {
+			return fmt.Errorf("export %q failed: %w", route.Path, err)
+		}
+	}
+	return nil
+}
+
+// exportPage renders the page of the _route
+func (ws WebServer) exportPage(_fsys fs.FS, _route ExportRoute) ([]byte, error) {
+	pagename := _route.Page
+	if pagename == "" {
+		pagename = "index.html"
+	}
+	page, err := fs.ReadFile(_fsys, strings.TrimPrefix(path.Clean(pagename), "/"))
+	if err != nil {
+		return nil, err
+	}
+	page = injectAppConfig(page, ws.AppConfig)
+	renderer := ws.newExportRenderer()
+	if page, _, err = renderer.render(page, 0); err != nil || _route.Markdown == "" {
+		return page, err
+	}
+
+	mdtxt, err := os.ReadFile(_route.Markdown)
+	if err != nil {
+		return nil, err
+	}
+	options := make([]goldmark.Option, 0)
+	if _route.Unsafe {
+		options = append(options, goldmark.WithRendererOptions(html.WithUnsafe()))
+	}
+	if _route.Highlight {
+		options = append(options, goldmark.WithExtensions(highlighting.Highlighting))
+	}
+	mdhtml, err := markdown.ConvertMarkdown(string(mdtxt), options...)
+	if err != nil {
+		return nil, err
+	}
+
+	// the markdown html is a template, executed with the route data as the wasm app does
+	tmpl, err := template.New(_route.Markdown).Parse(mdhtml)
+	if err != nil {
+		return nil, err
+	}
+	var content bytes.Buffer
+	if err := tmpl.Execute(&content, _route.Data); err != nil {
+		return nil, err
+	}
+	rendered, complete, err := renderer.render(content.Bytes(), 0)
+	if err != nil {
+		return nil, err
+	}
+	return insertIntoElement(page, _route.Target, rendered, complete)
+}
+
+// insertIntoElement inserts _content at the beginning of the _html element with the _id, and marks it as prerendered
+// if the _content is _complete.
+func insertIntoElement(_html []byte, _id string, _content []byte, _complete bool) ([]byte, error) {
+	rexp := regexp.MustCompile(`(?is)<[a-z][a-z0-9-]*\b[^>]*\sid\s*=\s*["']?` + regexp.QuoteMeta(_id) + `(?:["'\s][^>]*?)?(/?)>`)
+	loc := rexp.FindSubmatchIndex(_html)
+	if _id == "" || loc == nil {
+		return nil, fmt.Errorf("target element id=%q not found", _id)
+	}
+	if loc[3] > loc[2] {
+		return nil, fmt.Errorf("target element id=%q is self-closed", _id)
+	}
+	out := make([]byte, 0, len(_html)+len(_content)+32)
+	out = append(out, _html[:loc[1]-1]...)
+	if _complete {
+		out = append(out, " data-ick-prerendered"...)
+	}
+	out = append(out, '>')
+	out = append(out, _content...)
+	out = append(out, _html[loc[1]:]...)
+	return out, nil
+}
+
+/******************************************************************************
+* Server side components
+******************************************************************************/
+
+// ExportComponent renders server side a component of the pages written by Export, with the attributes of its <ick-*/> tag.
+// It returns the container element of the component with its content, as the wasm app renders them:
+//
+//	ws.ExportComponents = map[string]spaserver.ExportComponent{
+//		"ick-notify": func(_attrs map[string]string) (*h.ElementNode, error) {
+//			return h.Div(h.Class("notification"), h.Text(_attrs["Message"])), nil
+//		},
+//	}
+//
+// The content can embed other <ick-*/> tags, rendered in turn.
+type ExportComponent func(_attrs map[string]string) (*h.ElementNode, error)
+
+var (
+	rexpComponentTag = regexp.MustCompile(`<ick-([^\s/>]*)((?:[^>"']|"[^"]*"|'[^']*')*?)/>`)
+	rexpTagAttribute = regexp.MustCompile(`([^\s=/>"']+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'=<>` + "`" + `]+)))?`)
+)
+
+// exportRenderer renders the components of an exported page, numbering them within the page
+type exportRenderer struct {
+	components map[string]ExportComponent
+	count      map[string]int
+}
+
+func (ws WebServer) newExportRenderer() *exportRenderer {
+	return &exportRenderer{components: ws.ExportComponents, count: make(map[string]int)}
+}
+
+// render renders the <ick-*/> tags of the _html with their ExportComponent, recursively.
+// Returns false if some tags have no renderer, they're kept as is.
+func (_r *exportRenderer) render(_html []byte, _deep int) (_out []byte, _complete bool, _err error) {
+	if _deep >= 10 {
+		return nil, false, fmt.Errorf("rendering components stopped at level %d. Recursive rendering too deep", _deep)
+	}
+	_complete = true
+	_out = rexpComponentTag.ReplaceAllFunc(_html, func(tag []byte) []byte {
+		match := rexpComponentTag.FindSubmatch(tag)
+		name := "ick-" + strings.ToLower(string(match[1]))
+		renderfn, found := _r.components[name]
+		if !found || _err != nil {
+			_complete = false
+			return tag
+		}
+		attrs := parseTagAttributes(string(match[2]))
+		node, err := renderfn(attrs)
+		if err != nil {
+			_err = fmt.Errorf("rendering %q: %w", name, err)
+			return tag
+		}
+		_r.count[name]++
+		node.With(
+			h.Class(attrs["class"]),
+			h.Id(fmt.Sprintf("%s-s%d", name, _r.count[name])),
+			h.Data("ick-component", name),
+			h.Data("ick-attrs", strings.TrimSpace(string(match[2]))),
+			h.Data("ick-prerendered", ""))
+
+		// the content of the component can embed other components
+		rendered, complete, err := _r.render([]byte(h.HTML(node)), _deep+1)
+		if err != nil {
+			_err = err
+			return tag
+		}
+		_complete = _complete && complete
+		return rendered
+	})
+	if _err != nil {
+		return nil, false, _err
+	}
+	return _out, _complete, nil
+}
+
+// parseTagAttributes returns the attributes of the _attrs string of a tag, by name, with their unescaped value
+func parseTagAttributes(_attrs string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range rexpTagAttribute.FindAllStringSubmatch(_attrs, -1) {
+		attrs[match[1]] = stdhtml.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+// writeExportFile writes _data into the _name file of the _outdir directory
+func writeExportFile(_outdir string, _name string, _data []byte) error {
+	filename := filepath.Join(_outdir, filepath.FromSlash(_name))
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, _data, 0644)
+}
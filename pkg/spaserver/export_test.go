@@ -0,0 +1,147 @@
+package spaserver
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sunraylab/icecake/pkg/h"
+)
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "intro.md"), []byte("# Hello {{.Name}}\n\n<ick-notify Message=\"hi\"/>\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "routes.json"), []byte(`[
+		{"path": "/docs/", "markdown": "intro.md", "target": "content", "data": {"Name": "bob"}, "unsafe": true},
+		{"path": "/about.html", "page": "about.html"}
+	]`), 0644)
+
+	ws := WebServer{
+		StaticFS: fstest.MapFS{
+			"index.html":  {Data: []byte(`<script id="ick-config" type="application/json"></script><div class="block" id="content"></div>`)},
+			"about.html":  {Data: []byte(`about`)},
+			"css/app.css": {Data: []byte(`body{}`)},
+		},
+		AppConfig: map[string]any{"VERSION": "1.0"},
+	}
+	routes, err := LoadExportRoutes(filepath.Join(dir, "routes.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outdir := filepath.Join(dir, "dist")
+	if err := ws.Export(routes, outdir); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"index.html":      `<script id="ick-config" type="application/json">{"VERSION":"1.0"}</script><div class="block" id="content"></div>`,
+		"docs/index.html": `<script id="ick-config" type="application/json">{"VERSION":"1.0"}</script><div class="block" id="content"><h1>Hello bob</h1>` + "\n" + `<ick-notify Message="hi"/>` + "\n</div>",
+		"about.html":      `about`,
+		"css/app.css":     `body{}`,
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(outdir, name))
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		if string(data) != content {
+			t.Errorf("%s: unexpected content %q", name, string(data))
+		}
+	}
+
+	// components rendered server side
+	ws.ExportComponents = map[string]ExportComponent{
+		"ick-notify": func(_attrs map[string]string) (*h.ElementNode, error) {
+			return h.Div(h.Class("notification"), h.Text(_attrs["Message"]), h.UnsafeHTML(`<ick-delete/>`)), nil
+		},
+		"ick-delete": func(_attrs map[string]string) (*h.ElementNode, error) {
+			return h.Button(h.Class("delete")), nil
+		},
+	}
+	if err := ws.Export(routes, outdir); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(outdir, "docs", "index.html"))
+	content := `<script id="ick-config" type="application/json">{"VERSION":"1.0"}</script><div class="block" id="content" data-ick-prerendered><h1>Hello bob</h1>` + "\n" +
+		`<div class="notification" id="ick-notify-s1" data-ick-component="ick-notify" data-ick-attrs="Message=&#34;hi&#34;" data-ick-prerendered="">hi` +
+		`<button class="delete" id="ick-delete-s1" data-ick-component="ick-delete" data-ick-attrs="" data-ick-prerendered=""></button></div>` + "\n</div>"
+	if string(data) != content {
+		t.Errorf("unexpected rendered components %q", string(data))
+	}
+
+	// errors
+	for _, route := range []ExportRoute{{Path: "docs"}, {Path: "/docs"}, {Path: "/x/", Markdown: filepath.Join(dir, "intro.md"), Target: "missing"}} {
+		if err := ws.Export([]ExportRoute{route}, outdir); err == nil {
+			t.Errorf("route %+v must fail", route)
+		}
+	}
+}
+
+func TestInsertIntoElement(t *testing.T) {
+	tests := []struct {
+		html     string
+		id       string
+		complete bool
+		result   string
+	}{
+		{html: `<div id=a>x</div>`, id: "a", complete: true, result: `<div id=a data-ick-prerendered>Cx</div>`},
+		{html: `<div id=a>x</div>`, id: "a", result: `<div id=a>Cx</div>`},
+		{html: `<p id='ab'></p><div class="c" id="a" role="main"></div>`, id: "a", complete: true, result: `<p id='ab'></p><div class="c" id="a" role="main" data-ick-prerendered>C</div>`},
+		{html: `<div data-id="a"></div>`, id: "a"},
+		{html: `<ick-x id="a"/>`, id: "a"},
+	}
+	for _, tst := range tests {
+		out, err := insertIntoElement([]byte(tst.html), tst.id, []byte("C"), tst.complete)
+		if tst.result == "" {
+			if err == nil {
+				t.Errorf("%q: error expected, got %q", tst.html, string(out))
+			}
+			continue
+		}
+		if err != nil || string(out) != tst.result {
+			t.Errorf("%q: %q expected, got %q err=%v", tst.html, tst.result, string(out), err)
+		}
+	}
+}
+
+func TestExportRenderer(t *testing.T) {
+	renderer := &exportRenderer{
+		components: map[string]ExportComponent{
+			"ick-tag": func(_attrs map[string]string) (*h.ElementNode, error) {
+				return h.Span(h.Text(fmt.Sprint(_attrs))), nil
+			},
+			"ick-loop": func(_attrs map[string]string) (*h.ElementNode, error) {
+				return h.Div(h.UnsafeHTML(`<ick-loop/>`)), nil
+			},
+		},
+		count: make(map[string]int),
+	}
+	tests := []struct {
+		html     string
+		result   string
+		complete bool
+		err      bool
+	}{
+		{html: `<p>no component</p><script id="ick-config"></script>`, result: `<p>no component</p><script id="ick-config"></script>`, complete: true},
+		{html: `<ick-tag a="x/>" b='&lt;' c=d e class="on"/>`, complete: true,
+			result: `<span class="on" id="ick-tag-s1" data-ick-component="ick-tag" data-ick-attrs="a=&#34;x/&gt;&#34; b=&#39;&amp;lt;&#39; c=d e class=&#34;on&#34;" data-ick-prerendered="">map[a:x/&gt; b:&lt; c:d class:on e:]</span>`},
+		{html: `<ick-other x="1"/><br/>`, result: `<ick-other x="1"/><br/>`},
+		{html: `<ick-notify message="a"></ick-notify><br/>`, result: `<ick-notify message="a"></ick-notify><br/>`, complete: true},
+		{html: `<ick-loop/>`, err: true},
+	}
+	for i, tst := range tests {
+		out, complete, err := renderer.render([]byte(tst.html), 0)
+		if tst.err {
+			if err == nil {
+				t.Errorf("test %d: error expected", i)
+			}
+			continue
+		}
+		if err != nil || string(out) != tst.result || complete != tst.complete {
+			t.Errorf("test %d: %q %v expected, got %q %v err=%v", i, tst.result, tst.complete, string(out), complete, err)
+		}
+	}
+}
@@ -1,9 +1,17 @@
 package spaserver
 
 import (
+	"encoding/json"
 	"fmt"
+	"log"
+	"math"
+	"net"
 	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -43,3 +51,241 @@ func middlewareNoCache(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+/******************************************************************************
+* api middlewares
+******************************************************************************/
+
+// ApiConfig holds the protections applied to every /api requests.
+type ApiConfig struct {
+	CORSOrigins     []string // allowed origins for cross-origin requests, "*" for any, none to disable CORS
+	CORSMethods     []string // methods allowed for cross-origin requests
+	CORSHeaders     []string // request headers allowed for cross-origin requests
+	CORSCredentials bool     // allows cross-origin requests with credentials (cookies, authorization headers)
+
+	RateLimit float64 // maximum number of requests per second and per client IP, 0 disables rate limiting
+	RateBurst int     // maximum burst of requests per client IP
+
+	MaxBodySize int64 // maximum size of the request body in bytes, 0 for no limit
+}
+
+// LoadApiConfig returns the ApiConfig loaded from the API_CORS_ORIGINS, API_CORS_METHODS, API_CORS_HEADERS,
+// API_CORS_CREDENTIALS, API_RATELIMIT, API_RATELIMIT_BURST and API_MAXBODYSIZE env variables.
+func LoadApiConfig() ApiConfig {
+	cfg := ApiConfig{
+		CORSMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		CORSHeaders: []string{"Content-Type", "Authorization"},
+		MaxBodySize: 1 << 20,
+	}
+
+	cfg.CORSOrigins = splitEnvList("API_CORS_ORIGINS")
+	if methods := splitEnvList("API_CORS_METHODS"); len(methods) > 0 {
+		cfg.CORSMethods = methods
+		for i := range cfg.CORSMethods {
+			cfg.CORSMethods[i] = strings.ToUpper(cfg.CORSMethods[i])
+		}
+	}
+	if headers := splitEnvList("API_CORS_HEADERS"); len(headers) > 0 {
+		cfg.CORSHeaders = headers
+	}
+	cfg.CORSCredentials = strings.ToLower(strings.Trim(os.Getenv("API_CORS_CREDENTIALS"), " ")) == "true"
+
+	cfg.RateLimit, _ = strconv.ParseFloat(strings.Trim(os.Getenv("API_RATELIMIT"), " "), 64)
+	if cfg.RateLimit < 0 {
+		cfg.RateLimit = 0
+	}
+	cfg.RateBurst, _ = strconv.Atoi(strings.Trim(os.Getenv("API_RATELIMIT_BURST"), " "))
+	if cfg.RateBurst <= 0 {
+		cfg.RateBurst = int(math.Ceil(cfg.RateLimit * 2))
+	}
+
+	if size, err := strconv.ParseInt(strings.Trim(os.Getenv("API_MAXBODYSIZE"), " "), 10, 64); err == nil && size >= 0 {
+		cfg.MaxBodySize = size
+	}
+	return cfg
+}
+
+// splitEnvList returns the comma separated values of the _envkey variable
+func splitEnvList(_envkey string) []string {
+	list := make([]string, 0)
+	for _, v := range strings.Split(os.Getenv(_envkey), ",") {
+		if v = strings.Trim(v, " "); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// WriteProblem replies to the request with a json problem, according to RFC 7807.
+func WriteProblem(w http.ResponseWriter, _status int, _detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(_status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(_status),
+		"status": _status,
+		"detail": _detail,
+	})
+}
+
+// middlewareRecover recovers panics of the next handler, logs the stack and replies with a 500 json problem,
+// instead of dropping the connection.
+func middlewareRecover(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+				log.Printf("panic serving %s %s: %v\n%s", r.Method, r.RequestURI, rec, debug.Stack())
+				WriteProblem(w, http.StatusInternalServerError, "the server encountered an unexpected condition")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// middlewareCORS handles cross-origin requests and replies to preflight requests.
+func middlewareCORS(_cfg ApiConfig) func(http.Handler) http.Handler {
+	anyorigin := false
+	origins := make(map[string]bool, len(_cfg.CORSOrigins))
+	for _, o := range _cfg.CORSOrigins {
+		if o == "*" {
+			anyorigin = true
+		}
+		origins[strings.ToLower(strings.TrimRight(o, "/"))] = true
+	}
+	methods := strings.Join(_cfg.CORSMethods, ", ")
+	headers := strings.Join(_cfg.CORSHeaders, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			h := w.Header()
+			h.Add("Vary", "Origin")
+
+			if origin == "" || !(anyorigin || origins[strings.ToLower(origin)]) {
+				if preflight {
+					// not allowed, the browser will block the request
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if anyorigin && !_cfg.CORSCredentials {
+				h.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				h.Set("Access-Control-Allow-Origin", origin)
+			}
+			if _cfg.CORSCredentials {
+				h.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if preflight {
+				h.Add("Vary", "Access-Control-Request-Method")
+				h.Add("Vary", "Access-Control-Request-Headers")
+				h.Set("Access-Control-Allow-Methods", methods)
+				h.Set("Access-Control-Allow-Headers", headers)
+				h.Set("Access-Control-Max-Age", "600")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// middlewareMaxBodySize limits the size of request bodies, replying 413 when the Content-Length exceeds the limit.
+// Bodies without Content-Length are limited while being read by the handler.
+func middlewareMaxBodySize(_maxsize int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > _maxsize {
+				WriteProblem(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", _maxsize))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, _maxsize)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// rateLimiter is a per client IP token bucket rate limiter.
+type rateLimiter struct {
+	rate    float64 // tokens added per second
+	burst   float64 // bucket capacity
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	lastgc  time.Time
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func newRateLimiter(_rate float64, _burst int) *rateLimiter {
+	if _burst < 1 {
+		_burst = 1
+	}
+	return &rateLimiter{
+		rate:    _rate,
+		burst:   float64(_burst),
+		buckets: make(map[string]*tokenBucket),
+		lastgc:  time.Now(),
+	}
+}
+
+// allow consumes a token for _key. If no token is available, allow returns false and the delay before the next one.
+func (rl *rateLimiter) allow(_key string, _now time.Time) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	// forget idle clients whose bucket is full again
+	if _now.Sub(rl.lastgc) > time.Minute {
+		for key, b := range rl.buckets {
+			if b.tokens+_now.Sub(b.last).Seconds()*rl.rate >= rl.burst {
+				delete(rl.buckets, key)
+			}
+		}
+		rl.lastgc = _now
+	}
+
+	b, found := rl.buckets[_key]
+	if !found {
+		b = &tokenBucket{tokens: rl.burst, last: _now}
+		rl.buckets[_key] = b
+	}
+	b.tokens = math.Min(rl.burst, b.tokens+_now.Sub(b.last).Seconds()*rl.rate)
+	b.last = _now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	wait := time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+	return false, wait
+}
+
+// middlewareRateLimit replies 429 with a Retry-After header to clients exceeding the rate limit.
+// Clients are identified by their remote IP address.
+func middlewareRateLimit(_rate float64, _burst int) func(http.Handler) http.Handler {
+	rl := newRateLimiter(_rate, _burst)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				ip = r.RemoteAddr
+			}
+			if ok, wait := rl.allow(ip, time.Now()); !ok {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				WriteProblem(w, http.StatusTooManyRequests, "rate limit exceeded")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
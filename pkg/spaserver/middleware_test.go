@@ -0,0 +1,186 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// newApiTestServer returns a WebServer with a protected /api subrouter
+func newApiTestServer(_cfg ApiConfig) WebServer {
+	ws := WebServer{Api: _cfg}
+	ws.WebRouter = mux.NewRouter()
+	ws.ApiRouter = ws.WebRouter.PathPrefix("/api").Subrouter()
+	ws.ApiRouter.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			WriteProblem(w, http.StatusRequestEntityTooLarge, err.Error())
+			return
+		}
+		w.Write([]byte("ok"))
+	}).Methods(http.MethodPost)
+	ws.ApiRouter.HandleFunc("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	ws.protectApi()
+	return ws
+}
+
+func TestApiCORS(t *testing.T) {
+	ws := newApiTestServer(ApiConfig{
+		CORSOrigins:     []string{"https://front.example.com"},
+		CORSMethods:     []string{http.MethodGet, http.MethodPost},
+		CORSHeaders:     []string{"Content-Type"},
+		CORSCredentials: true,
+	})
+
+	tests := []struct {
+		method      string
+		origin      string
+		preflight   bool
+		status      int
+		allowOrigin string
+	}{
+		{method: http.MethodOptions, origin: "https://front.example.com", preflight: true, status: http.StatusNoContent, allowOrigin: "https://front.example.com"},
+		{method: http.MethodOptions, origin: "https://evil.example.com", preflight: true, status: http.StatusNoContent, allowOrigin: ""},
+		{method: http.MethodPost, origin: "https://front.example.com", status: http.StatusOK, allowOrigin: "https://front.example.com"},
+		{method: http.MethodPost, origin: "https://evil.example.com", status: http.StatusOK, allowOrigin: ""},
+		{method: http.MethodPost, status: http.StatusOK, allowOrigin: ""},
+	}
+
+	for i, tst := range tests {
+		req := httptest.NewRequest(tst.method, "/api/echo", strings.NewReader("{}"))
+		if tst.origin != "" {
+			req.Header.Set("Origin", tst.origin)
+		}
+		if tst.preflight {
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		}
+		rec := httptest.NewRecorder()
+		ws.WebRouter.ServeHTTP(rec, req)
+		if rec.Code != tst.status {
+			t.Errorf("test %d: status %v expected, got %v", i, tst.status, rec.Code)
+		}
+		if ao := rec.Header().Get("Access-Control-Allow-Origin"); ao != tst.allowOrigin {
+			t.Errorf("test %d: Access-Control-Allow-Origin %q expected, got %q", i, tst.allowOrigin, ao)
+		}
+		if tst.allowOrigin != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("test %d: Access-Control-Allow-Credentials expected", i)
+		}
+		if tst.preflight && tst.allowOrigin != "" && rec.Header().Get("Access-Control-Allow-Methods") != "GET, POST" {
+			t.Errorf("test %d: unexpected Access-Control-Allow-Methods %q", i, rec.Header().Get("Access-Control-Allow-Methods"))
+		}
+	}
+}
+
+func TestApiRateLimit(t *testing.T) {
+	ws := newApiTestServer(ApiConfig{RateLimit: 1, RateBurst: 2})
+
+	status := make([]int, 0, 3)
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		ws.WebRouter.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/echo", nil))
+		status = append(status, rec.Code)
+		if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "1" {
+			t.Errorf("Retry-After 1 expected, got %q", rec.Header().Get("Retry-After"))
+		}
+	}
+	if status[0] != http.StatusOK || status[1] != http.StatusOK || status[2] != http.StatusTooManyRequests {
+		t.Errorf("unexpected status sequence %v", status)
+	}
+
+	// other clients are not limited
+	req := httptest.NewRequest(http.MethodPost, "/api/echo", nil)
+	req.RemoteAddr = "192.0.2.99:1234"
+	rec := httptest.NewRecorder()
+	ws.WebRouter.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("another client must not be limited, got %v", rec.Code)
+	}
+}
+
+func TestRateLimiterRefill(t *testing.T) {
+	rl := newRateLimiter(2, 1)
+	now := time.Now()
+	if ok, _ := rl.allow("a", now); !ok {
+		t.Fatalf("first request must be allowed")
+	}
+	ok, wait := rl.allow("a", now)
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("second request must wait 500ms, got %v %v", ok, wait)
+	}
+	if ok, _ := rl.allow("a", now.Add(500*time.Millisecond)); !ok {
+		t.Errorf("request must be allowed after refill")
+	}
+}
+
+func TestApiMaxBodySize(t *testing.T) {
+	ws := newApiTestServer(ApiConfig{MaxBodySize: 8})
+
+	tests := []struct {
+		body    string
+		chunked bool
+		status  int
+	}{
+		{body: "small", status: http.StatusOK},
+		{body: "far too large body", status: http.StatusRequestEntityTooLarge},
+		{body: "far too large body", chunked: true, status: http.StatusRequestEntityTooLarge},
+	}
+	for i, tst := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/echo", strings.NewReader(tst.body))
+		if tst.chunked {
+			req.ContentLength = -1
+		}
+		rec := httptest.NewRecorder()
+		ws.WebRouter.ServeHTTP(rec, req)
+		if rec.Code != tst.status {
+			t.Errorf("test %d: status %v expected, got %v", i, tst.status, rec.Code)
+		}
+	}
+}
+
+func TestApiRecover(t *testing.T) {
+	ws := newApiTestServer(ApiConfig{})
+
+	rec := httptest.NewRecorder()
+	ws.WebRouter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/panic", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status 500 expected, got %v", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Errorf("unexpected content-type %q", ct)
+	}
+	var problem struct {
+		Title  string
+		Status int
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&problem); err != nil || problem.Status != http.StatusInternalServerError {
+		t.Errorf("unexpected problem %+v, err=%v", problem, err)
+	}
+}
+
+func TestLoadApiConfig(t *testing.T) {
+	t.Setenv("API_CORS_ORIGINS", " https://a.example.com , https://b.example.com")
+	t.Setenv("API_CORS_METHODS", "get,post")
+	t.Setenv("API_RATELIMIT", "5")
+	t.Setenv("API_MAXBODYSIZE", "1024")
+
+	cfg := LoadApiConfig()
+	if len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[1] != "https://b.example.com" {
+		t.Errorf("unexpected origins %v", cfg.CORSOrigins)
+	}
+	if strings.Join(cfg.CORSMethods, ",") != "GET,POST" {
+		t.Errorf("unexpected methods %v", cfg.CORSMethods)
+	}
+	if cfg.RateLimit != 5 || cfg.RateBurst != 10 {
+		t.Errorf("unexpected rate limit %v burst %v", cfg.RateLimit, cfg.RateBurst)
+	}
+	if cfg.MaxBodySize != 1024 {
+		t.Errorf("unexpected max body size %v", cfg.MaxBodySize)
+	}
+}
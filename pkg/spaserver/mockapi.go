@@ -0,0 +1,317 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"io/fs"
+	"log"
+	"math/rand"
+	"mime"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+)
+
+// mockapi_maxRecordSize is the maximum size of a recorded response body
+const mockapi_maxRecordSize = 10 << 20
+
+/******************************************************************************
+* MockFixture
+******************************************************************************/
+
+// MockFixture is a mocked api response, loaded from a json fixture file.
+// A fixture file holds a single fixture object or an array of fixtures.
+type MockFixture struct {
+	Method      string            `json:"method,omitempty"`      // the request method, any method if empty
+	Path        string            `json:"path"`                  // the path pattern relative to /api, with mux variables, ie. "/users/{id:[0-9]+}"
+	Status      int               `json:"status,omitempty"`      // the response status code, 200 by default
+	Headers     map[string]string `json:"headers,omitempty"`     // the response headers
+	Body        json.RawMessage   `json:"body,omitempty"`        // a json body, sent with the application/json content-type by default
+	Text        string            `json:"text,omitempty"`        // a text body, used if there's no json body
+	File        string            `json:"file,omitempty"`        // a file body, relative to the fixture file, used if there's neither json nor text body
+	Latency     string            `json:"latency,omitempty"`     // a delay before responding, ie. "250ms"
+	ErrorRate   float64           `json:"errorRate,omitempty"`   // the probability to respond with an error instead of the fixture, between 0 and 1
+	ErrorStatus int               `json:"errorStatus,omitempty"` // the status of injected errors, 500 by default
+
+	dir     string        // the directory of the fixture file
+	latency time.Duration // the parsed Latency
+}
+
+// LoadMockFixtures loads the fixtures of every json files within the _dir directory and its sub-directories.
+// Fixtures are sorted by file names, the first fixture matching a request responds to it.
+func LoadMockFixtures(_dir string) ([]*MockFixture, error) {
+	fixtures := make([]*MockFixture, 0)
+	err := filepath.WalkDir(_dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(name) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		list := make([]*MockFixture, 0)
+		if trimmed := bytes.TrimLeft(data, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+			err = json.Unmarshal(data, &list)
+		} else {
+			f := new(MockFixture)
+			err = json.Unmarshal(data, f)
+			list = append(list, f)
+		}
+		if err != nil {
+			return fmt.Errorf("fixture %q: %w", name, err)
+		}
+		for _, f := range list {
+			if err := f.init(filepath.Dir(name)); err != nil {
+				return fmt.Errorf("fixture %q: %w", name, err)
+			}
+		}
+		fixtures = append(fixtures, list...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("loading mock fixtures failed: %w", err)
+	}
+	return fixtures, nil
+}
+
+// init checks and completes the fixture loaded from the _dir directory
+func (_f *MockFixture) init(_dir string) (_err error) {
+	if !strings.HasPrefix(_f.Path, "/") {
+		return fmt.Errorf("path %q must start with /", _f.Path)
+	}
+	_f.Method = strings.ToUpper(strings.Trim(_f.Method, " "))
+	if _f.Status == 0 {
+		_f.Status = http.StatusOK
+	}
+	if _f.ErrorStatus == 0 {
+		_f.ErrorStatus = http.StatusInternalServerError
+	}
+	if _f.ErrorRate < 0 || _f.ErrorRate > 1 {
+		return fmt.Errorf("errorRate %v must be between 0 and 1", _f.ErrorRate)
+	}
+	if _f.Latency != "" {
+		if _f.latency, _err = time.ParseDuration(_f.Latency); _err != nil {
+			return fmt.Errorf("invalid latency: %w", _err)
+		}
+	}
+	_f.dir = _dir
+	return nil
+}
+
+// ServeHTTP responds with the fixture, after its latency, or with an injected error.
+func (_f *MockFixture) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if _f.latency > 0 {
+		select {
+		case <-time.After(_f.latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if _f.ErrorRate > 0 && rand.Float64() < _f.ErrorRate {
+		WriteProblem(w, _f.ErrorStatus, "mock api injected error")
+		return
+	}
+
+	var body []byte
+	switch {
+	case len(_f.Body) > 0:
+		body = _f.Body
+		w.Header().Set("Content-Type", "application/json")
+	case _f.Text != "":
+		body = []byte(_f.Text)
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	case _f.File != "":
+		var err error
+		if body, err = os.ReadFile(filepath.Join(_f.dir, _f.File)); err != nil {
+			log.Printf("spa server: mock api %s %s: %s", r.Method, r.URL.Path, err)
+			WriteProblem(w, http.StatusInternalServerError, "mock api fixture file not found")
+			return
+		}
+		if ctype := mime.TypeByExtension(filepath.Ext(_f.File)); ctype != "" {
+			w.Header().Set("Content-Type", ctype)
+		}
+	}
+	for key, value := range _f.Headers {
+		w.Header().Set(key, value)
+	}
+	w.WriteHeader(_f.Status)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+// RegisterMockFixtures adds a route to the _router for every fixture.
+func RegisterMockFixtures(_router *mux.Router, _fixtures []*MockFixture) {
+	for _, f := range _fixtures {
+		route := _router.Handle(f.Path, f)
+		if f.Method != "" {
+			route.Methods(f.Method)
+		}
+	}
+}
+
+/******************************************************************************
+* MockRecorder
+******************************************************************************/
+
+// MockRecorder proxies requests to a real backend, and saves every response as a fixture file.
+// The recorded fixtures can then be served in replay mode.
+type MockRecorder struct {
+	dir    string // the fixtures directory
+	prefix string // the path prefix removed from recorded fixture paths
+	proxy  *httputil.ReverseProxy
+}
+
+// NewMockRecorder is the MockRecorder factory. Requests are forwarded to _upstream, and fixtures are written to _dir.
+// _prefix is removed from the request path to get the fixture path, usually "/api".
+func NewMockRecorder(_upstream string, _dir string, _prefix string) (*MockRecorder, error) {
+	target, err := url.Parse(_upstream)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		return nil, fmt.Errorf("invalid mock recorder upstream url %q", _upstream)
+	}
+	if err := os.MkdirAll(_dir, 0755); err != nil {
+		return nil, fmt.Errorf("mock recorder: %w", err)
+	}
+
+	rec := &MockRecorder{dir: _dir, prefix: _prefix}
+	rec.proxy = httputil.NewSingleHostReverseProxy(target)
+	director := rec.proxy.Director
+	rec.proxy.Director = func(r *http.Request) {
+		director(r)
+		r.Host = target.Host
+		// recorded bodies must not be compressed
+		r.Header.Del("Accept-Encoding")
+	}
+	rec.proxy.ModifyResponse = rec.record
+	return rec, nil
+}
+
+// ServeHTTP forwards the request to the upstream backend.
+func (_rec *MockRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	_rec.proxy.ServeHTTP(w, r)
+}
+
+// headers not recorded within fixtures
+var mockapi_skipHeaders = map[string]bool{
+	"Content-Length":    true,
+	"Content-Type":      true,
+	"Content-Encoding":  true,
+	"Transfer-Encoding": true,
+	"Connection":        true,
+	"Date":              true,
+	"Set-Cookie":        true,
+}
+
+// record saves the upstream response as a fixture, and restores its body to be forwarded to the client
+func (_rec *MockRecorder) record(_resp *http.Response) error {
+	upstream := _resp.Body
+	body, err := io.ReadAll(io.LimitReader(upstream, mockapi_maxRecordSize+1))
+	if err != nil {
+		return err
+	}
+	if len(body) > mockapi_maxRecordSize {
+		// too large to be recorded, forward it as is
+		_resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), upstream), upstream}
+		log.Printf("spa server: mock recorder: %s %s response too large to be recorded", _resp.Request.Method, _resp.Request.URL.Path)
+		return nil
+	}
+	upstream.Close()
+	_resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	req := _resp.Request
+	f := MockFixture{
+		Method:  req.Method,
+		Path:    mockEscapePath(strings.TrimPrefix(req.URL.Path, _rec.prefix)),
+		Status:  _resp.StatusCode,
+		Headers: make(map[string]string),
+	}
+	name := mockFixtureName(f.Method, strings.TrimPrefix(req.URL.Path, _rec.prefix))
+
+	ctype := _resp.Header.Get("Content-Type")
+	mediatype, _, _ := mime.ParseMediaType(ctype)
+	switch {
+	case len(body) == 0:
+	case strings.HasSuffix(mediatype, "json") && json.Valid(body):
+		f.Body = body
+		if mediatype != "application/json" {
+			f.Headers["Content-Type"] = ctype
+		}
+	case strings.HasPrefix(mediatype, "text/") && utf8.Valid(body):
+		f.Text = string(body)
+		f.Headers["Content-Type"] = ctype
+	default:
+		f.File = name + ".body"
+		if ctype != "" {
+			f.Headers["Content-Type"] = ctype
+		}
+		if err := os.WriteFile(filepath.Join(_rec.dir, f.File), body, 0644); err != nil {
+			log.Printf("spa server: mock recorder: %s", err)
+			return nil
+		}
+	}
+	for key := range _resp.Header {
+		if !mockapi_skipHeaders[key] {
+			f.Headers[key] = _resp.Header.Get(key)
+		}
+	}
+	if len(f.Headers) == 0 {
+		f.Headers = nil
+	}
+
+	data, _ := json.MarshalIndent(f, "", "  ")
+	if err := os.WriteFile(filepath.Join(_rec.dir, name+".json"), data, 0644); err != nil {
+		log.Printf("spa server: mock recorder: %s", err)
+	}
+	return nil
+}
+
+var rexpFixtureName = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// mockFixtureName returns the file name, without extension, of the fixture recorded for the _method and _path.
+// The name ends with a hash of the path, so paths with the same letters like "/users/1" and "/users_1" get their own fixture.
+func mockFixtureName(_method string, _path string) string {
+	name := strings.Trim(rexpFixtureName.ReplaceAllString(_path, "_"), "_")
+	if name == "" {
+		name = "root"
+	}
+	hash := fnv.New32a()
+	hash.Write([]byte(_path))
+	return fmt.Sprintf("%s_%s_%08x", strings.ToLower(_method), name, hash.Sum32())
+}
+
+// mockEscapePath returns the recorded _path as a mux path pattern, its braces being matched by variables
+// instead of delimiting variables
+func mockEscapePath(_path string) string {
+	var out strings.Builder
+	n := 0
+	for _, r := range _path {
+		switch r {
+		case '{':
+			fmt.Fprintf(&out, `{lbrace%d:\x7b}`, n)
+			n++
+		case '}':
+			fmt.Fprintf(&out, `{rbrace%d:\x7d}`, n)
+			n++
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
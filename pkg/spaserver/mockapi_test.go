@@ -0,0 +1,119 @@
+package spaserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestMockFixtures(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "users.json"), []byte(`[
+		{"method": "GET", "path": "/users/{id:[0-9]+}", "body": {"name": "bob"}, "headers": {"X-Mock": "yes"}},
+		{"method": "POST", "path": "/users", "status": 201, "text": "created"},
+		{"path": "/down", "errorRate": 1, "errorStatus": 503, "latency": "1ms"}
+	]`), 0644)
+	os.WriteFile(filepath.Join(dir, "logo.json"), []byte(`{"path": "/logo", "file": "logo.svg"}`), 0644)
+	os.WriteFile(filepath.Join(dir, "logo.svg"), []byte(`<svg/>`), 0644)
+
+	fixtures, err := LoadMockFixtures(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	RegisterMockFixtures(router.PathPrefix("/api").Subrouter(), fixtures)
+
+	tests := []struct {
+		method      string
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{method: http.MethodGet, path: "/api/users/42", status: http.StatusOK, contentType: "application/json", body: `{"name": "bob"}`},
+		{method: http.MethodGet, path: "/api/users/bob", status: http.StatusNotFound},
+		{method: http.MethodPost, path: "/api/users", status: http.StatusCreated, contentType: "text/plain; charset=utf-8", body: "created"},
+		{method: http.MethodDelete, path: "/api/users", status: http.StatusMethodNotAllowed},
+		{method: http.MethodGet, path: "/api/down", status: http.StatusServiceUnavailable, contentType: "application/problem+json"},
+		{method: http.MethodGet, path: "/api/logo", status: http.StatusOK, contentType: "image/svg+xml", body: "<svg/>"},
+	}
+	for _, tst := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tst.method, tst.path, nil))
+		if rec.Code != tst.status {
+			t.Errorf("%s %s: status %v expected, got %v", tst.method, tst.path, tst.status, rec.Code)
+			continue
+		}
+		if tst.contentType != "" && rec.Header().Get("Content-Type") != tst.contentType {
+			t.Errorf("%s %s: content-type %q expected, got %q", tst.method, tst.path, tst.contentType, rec.Header().Get("Content-Type"))
+		}
+		if body, _ := io.ReadAll(rec.Body); tst.body != "" && string(body) != tst.body {
+			t.Errorf("%s %s: unexpected body %q", tst.method, tst.path, string(body))
+		}
+	}
+
+	os.WriteFile(filepath.Join(dir, "bad.json"), []byte(`{"path": "nopath"}`), 0644)
+	if _, err := LoadMockFixtures(dir); err == nil {
+		t.Errorf("invalid fixture path must fail")
+	}
+}
+
+func TestMockRecorder(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Total", "1")
+		w.Write([]byte(`{"id":7}`))
+	}))
+	defer backend.Close()
+
+	dir := t.TempDir()
+	recorder, err := NewMockRecorder(backend.URL, dir, "/api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	recorder.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items/7", nil))
+	if body, _ := io.ReadAll(rec.Body); string(body) != `{"id":7}` {
+		t.Fatalf("unexpected proxied body %q", string(body))
+	}
+
+	// replay the recorded fixture
+	fixtures, err := LoadMockFixtures(dir)
+	if err != nil || len(fixtures) != 1 {
+		t.Fatalf("one recorded fixture expected, got %v, err=%v", len(fixtures), err)
+	}
+	f := fixtures[0]
+	if f.Method != http.MethodGet || f.Path != "/items/7" || f.Headers["X-Total"] != "1" {
+		t.Errorf("unexpected recorded fixture %+v", f)
+	}
+	rec = httptest.NewRecorder()
+	f.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/items/7", nil))
+	var compact bytes.Buffer
+	if body, _ := io.ReadAll(rec.Body); json.Compact(&compact, body) != nil || compact.String() != `{"id":7}` {
+		t.Errorf("unexpected replayed body %q", string(body))
+	}
+
+	// paths with the same fixture letters, and literal braces
+	for _, path := range []string{"/api/items_7", "/api/q/{x}"} {
+		recorder.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	if fixtures, err = LoadMockFixtures(dir); err != nil || len(fixtures) != 3 {
+		t.Fatalf("three recorded fixtures expected, got %v, err=%v", len(fixtures), err)
+	}
+	router := mux.NewRouter()
+	RegisterMockFixtures(router.PathPrefix("/api").Subrouter(), fixtures)
+	for path, code := range map[string]int{"/api/q/{x}": http.StatusOK, "/api/q/x": http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Errorf("%s: status %d expected, got %d", path, code, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package spaserver
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// ProxyRoute forwards the requests matching a path prefix to an upstream server,
+// so the spa and a separate backend are served from the same origin in development.
+type ProxyRoute struct {
+	Prefix      string            // the path prefix of the forwarded requests, ie. "/api"
+	Upstream    string            // the upstream server url, ie. "http://localhost:8080"
+	StripPrefix bool              // removes the prefix from the forwarded request path
+	Headers     map[string]string // headers set on forwarded requests, an empty value removes the header
+}
+
+// the SPA_PROXY route option removing the prefix from the forwarded request path
+const proxy_stripOption = ";strip"
+
+// LoadProxyRoutes returns the proxy routes defined with the SPA_PROXY env variable, as a comma separated list of prefix=upstream,
+// ie. "/api=http://localhost:8080". The prefix is removed from the forwarded path when the upstream is followed by ";strip",
+// ie. "/api=http://localhost:8080;strip". Headers set on forwarded requests are defined with the SPA_PROXY_HEADERS env variable,
+// as a comma separated list of name:value.
+func LoadProxyRoutes() ([]ProxyRoute, error) {
+	headers := make(map[string]string)
+	for _, h := range splitEnvList("SPA_PROXY_HEADERS") {
+		name, value, found := strings.Cut(h, ":")
+		if !found || strings.Trim(name, " ") == "" {
+			return nil, fmt.Errorf("invalid SPA_PROXY_HEADERS %q, name:value expected", h)
+		}
+		headers[http.CanonicalHeaderKey(strings.Trim(name, " "))] = strings.Trim(value, " ")
+	}
+
+	routes := make([]ProxyRoute, 0)
+	for _, r := range splitEnvList("SPA_PROXY") {
+		prefix, upstream, found := strings.Cut(r, "=")
+		if !found {
+			return nil, fmt.Errorf("invalid SPA_PROXY route %q, prefix=upstream expected", r)
+		}
+		upstream = strings.Trim(upstream, " ")
+		strip := strings.HasSuffix(upstream, proxy_stripOption)
+		upstream = strings.TrimSuffix(upstream, proxy_stripOption)
+		route := ProxyRoute{Prefix: strings.Trim(prefix, " "), Upstream: upstream, StripPrefix: strip, Headers: headers}
+		if _, err := route.target(); err != nil {
+			return nil, err
+		}
+		routes = append(routes, route)
+	}
+	return routes, nil
+}
+
+// target returns the parsed upstream url
+func (_route ProxyRoute) target() (*url.URL, error) {
+	if !strings.HasPrefix(_route.Prefix, "/") {
+		return nil, fmt.Errorf("invalid proxy prefix %q, must start with /", _route.Prefix)
+	}
+	target, err := url.Parse(_route.Upstream)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid proxy upstream %q, http or https url expected", _route.Upstream)
+	}
+	return target, nil
+}
+
+// match is the mux.MatcherFunc of the requests to forward: the path is the prefix or starts with the prefix followed by a /,
+// so "/api" matches "/api" and "/api/users" but not "/apifoo"
+func (_route ProxyRoute) match(_r *http.Request, _ *mux.RouteMatch) bool {
+	prefix := strings.TrimRight(_route.Prefix, "/")
+	return _r.URL.Path == prefix || strings.HasPrefix(_r.URL.Path, prefix+"/")
+}
+
+// handler returns the reverse proxy forwarding requests to the upstream server.
+// _timeout limits the connection to the upstream and the wait of its response headers.
+// Websocket upgrades are passed through, without timeout once upgraded.
+func (_route ProxyRoute) handler(_timeout time.Duration) (http.Handler, error) {
+	target, err := _route.target()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: _timeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.ResponseHeaderTimeout = _timeout
+
+	prefix := strings.TrimRight(_route.Prefix, "/")
+	proxy := &httputil.ReverseProxy{
+		Transport: transport,
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			if _route.StripPrefix {
+				pr.Out.URL.Path = strings.TrimPrefix(pr.Out.URL.Path, prefix)
+				pr.Out.URL.RawPath = ""
+			}
+			pr.SetURL(target)
+			pr.SetXForwarded()
+			for name, value := range _route.Headers {
+				if value == "" {
+					pr.Out.Header.Del(name)
+				} else {
+					pr.Out.Header.Set(name, value)
+				}
+			}
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			// redirections to the upstream are made relative to the spa server
+			if loc, err := url.Parse(resp.Header.Get("Location")); err == nil && loc.Host == target.Host {
+				loc.Scheme, loc.Host = "", ""
+				if _route.StripPrefix {
+					loc.Path = prefix + loc.Path
+				}
+				resp.Header.Set("Location", loc.String())
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("spa server: proxy %s %s to %q failed: %s", r.Method, r.URL.Path, _route.Upstream, err)
+			WriteProblem(w, http.StatusBadGateway, "upstream server unavailable")
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			// the upgraded connection lasts longer than the server read and write timeouts
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(time.Time{})
+			rc.SetWriteDeadline(time.Time{})
+		}
+		proxy.ServeHTTP(w, r)
+	}), nil
+}
@@ -0,0 +1,117 @@
+package spaserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestProxyRoute(t *testing.T) {
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.Redirect(w, r, "http://"+r.Host+"/home", http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-Got-Forwarded-Host", r.Header.Get("X-Forwarded-Host"))
+		w.Header().Set("X-Got-Dev-User", r.Header.Get("X-Dev-User"))
+		w.Header().Set("X-Got-Cookie", r.Header.Get("Cookie"))
+	}))
+	defer backend.Close()
+
+	route := ProxyRoute{
+		Prefix:      "/backend",
+		Upstream:    backend.URL,
+		StripPrefix: true,
+		Headers:     map[string]string{"X-Dev-User": "alice", "Cookie": ""},
+	}
+	handler, err := route.handler(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.MatcherFunc(route.match).Handler(handler)
+	router.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.Header().Set("X-Path", "spa") })
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/backendfoo", nil))
+	if rec.Header().Get("X-Path") != "spa" {
+		t.Errorf("/backendfoo must not be proxied, got %v", rec.Header())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "http://spa.example.com/backend/users", nil)
+	req.Header.Set("Cookie", "session=1")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	h := rec.Header()
+	if h.Get("X-Path") != "/users" || h.Get("X-Got-Forwarded-Host") != "spa.example.com" || h.Get("X-Got-Dev-User") != "alice" || h.Get("X-Got-Cookie") != "" {
+		t.Errorf("unexpected forwarded request %v", h)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/backend/login", nil))
+	if loc := rec.Header().Get("Location"); rec.Code != http.StatusFound || loc != "/backend/home" {
+		t.Errorf("redirection to /backend/home expected, got %v %q", rec.Code, loc)
+	}
+}
+
+func TestProxyRouteUnavailable(t *testing.T) {
+	handler, err := ProxyRoute{Prefix: "/api", Upstream: "http://127.0.0.1:1"}.handler(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/x", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Errorf("status 502 expected, got %v", rec.Code)
+	}
+}
+
+func TestProxyRouteWebsocket(t *testing.T) {
+	hub := NewWSHub()
+	backend := httptest.NewServer(hub)
+	defer backend.Close()
+
+	handler, err := ProxyRoute{Prefix: "/ws", Upstream: backend.URL, StripPrefix: true}.handler(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	conn := dialHub(t, srv)
+	defer conn.Close()
+	conn.WriteJSON(WSMessage{Type: WSMSG_SUBSCRIBE, Topic: "news"})
+	waitSubscribers(t, hub, "news", 1)
+	hub.Publish("news", "through the proxy")
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg WSMessage
+	if err := conn.ReadJSON(&msg); err != nil || string(msg.Data) != `"through the proxy"` {
+		t.Errorf("unexpected message %+v, err=%v", msg, err)
+	}
+}
+
+func TestLoadProxyRoutes(t *testing.T) {
+	t.Setenv("SPA_PROXY", "/api=http://localhost:8080, /auth=https://auth.example.com/v1;strip")
+	t.Setenv("SPA_PROXY_HEADERS", "X-Dev-User: alice")
+	routes, err := LoadProxyRoutes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 || routes[1].Prefix != "/auth" || routes[1].Upstream != "https://auth.example.com/v1" || routes[0].Headers["X-Dev-User"] != "alice" ||
+		routes[0].StripPrefix || !routes[1].StripPrefix {
+		t.Errorf("unexpected routes %+v", routes)
+	}
+
+	for _, bad := range []string{"/api", "api=http://localhost", "/api=localhost:8080"} {
+		t.Setenv("SPA_PROXY", bad)
+		if _, err := LoadProxyRoutes(); err == nil || !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("%q: invalid route error expected, got %v", bad, err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package spaserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CSP_NONCE is the placeholder replaced by the per-request nonce in the Content-Security-Policy
+const CSP_NONCE = "{nonce}"
+
+// SecurityHeaders holds the security headers added to every response by the security middleware.
+// An empty value disables the corresponding header.
+type SecurityHeaders struct {
+	CSP                string // Content-Security-Policy, the CSP_NONCE placeholder is replaced by a per-request nonce
+	FrameAncestors     string // frame-ancestors directive appended to the Content-Security-Policy
+	HSTS               string // Strict-Transport-Security, sent only over TLS
+	ContentTypeOptions string // X-Content-Type-Options
+	ReferrerPolicy     string // Referrer-Policy
+	PermissionsPolicy  string // Permissions-Policy
+}
+
+// DefaultSecurityHeaders returns strict security headers suitable for a go wasm spa:
+// the CSP allows 'wasm-unsafe-eval' to compile the wasm code, but no inline scripts nor styles
+// except the ones marked with `nonce="{nonce}"`, stamped with the per-request nonce.
+func DefaultSecurityHeaders() SecurityHeaders {
+	return SecurityHeaders{
+		CSP: "default-src 'self'; " +
+			"script-src 'self' 'nonce-" + CSP_NONCE + "' 'wasm-unsafe-eval'; " +
+			"style-src 'self' 'nonce-" + CSP_NONCE + "' https:; " +
+			"font-src 'self' https: data:; " +
+			"img-src 'self' https: data:; " +
+			"connect-src 'self'; " +
+			"object-src 'none'; " +
+			"base-uri 'self'; " +
+			"form-action 'self'",
+		FrameAncestors:     "'none'",
+		HSTS:               "max-age=63072000; includeSubDomains",
+		ContentTypeOptions: "nosniff",
+		ReferrerPolicy:     "strict-origin-when-cross-origin",
+		PermissionsPolicy:  "camera=(), microphone=(), geolocation=(), payment=()",
+	}
+}
+
+// LoadSecurityHeaders returns the DefaultSecurityHeaders overwritten by the HTTP_CSP, HTTP_CSP_FRAMEANCESTORS, HTTP_HSTS,
+// HTTP_CONTENTTYPEOPTIONS, HTTP_REFERRERPOLICY and HTTP_PERMISSIONSPOLICY env variables.
+// A variable set to "off" disables the header.
+func LoadSecurityHeaders() SecurityHeaders {
+	sh := DefaultSecurityHeaders()
+	lookupHeader(&sh.CSP, "HTTP_CSP")
+	lookupHeader(&sh.FrameAncestors, "HTTP_CSP_FRAMEANCESTORS")
+	lookupHeader(&sh.HSTS, "HTTP_HSTS")
+	lookupHeader(&sh.ContentTypeOptions, "HTTP_CONTENTTYPEOPTIONS")
+	lookupHeader(&sh.ReferrerPolicy, "HTTP_REFERRERPOLICY")
+	lookupHeader(&sh.PermissionsPolicy, "HTTP_PERMISSIONSPOLICY")
+	return sh
+}
+
+func lookupHeader(_header *string, _envkey string) {
+	if value, found := os.LookupEnv(_envkey); found {
+		value = strings.Trim(value, " ")
+		if strings.ToLower(value) == "off" {
+			value = ""
+		}
+		*_header = value
+	}
+}
+
+// policy returns the Content-Security-Policy with the nonce and the frame-ancestors directive
+func (sh SecurityHeaders) policy(_nonce string) string {
+	csp := strings.TrimRight(strings.Trim(sh.CSP, " "), ";")
+	if sh.FrameAncestors != "" {
+		if csp != "" {
+			csp += "; "
+		}
+		csp += "frame-ancestors " + sh.FrameAncestors
+	}
+	return strings.ReplaceAll(csp, CSP_NONCE, _nonce)
+}
+
+/******************************************************************************
+* security middleware
+******************************************************************************/
+
+type contextKey int
+
+const ctxkey_nonce contextKey = 0
+
+// Nonce returns the CSP nonce generated by the security middleware for this request,
+// or an empty string if the security middleware is off.
+func Nonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(ctxkey_nonce).(string)
+	return nonce
+}
+
+// newNonce returns a random base64 nonce
+func newNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// middlewareSecurity adds the security headers to every responses, with a new CSP nonce per request
+func middlewareSecurity(_sh SecurityHeaders) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			if _sh.CSP != "" || _sh.FrameAncestors != "" {
+				nonce := newNonce()
+				r = r.WithContext(context.WithValue(r.Context(), ctxkey_nonce, nonce))
+				h.Set("Content-Security-Policy", _sh.policy(nonce))
+			}
+			if _sh.HSTS != "" && r.TLS != nil {
+				h.Set("Strict-Transport-Security", _sh.HSTS)
+			}
+			if _sh.ContentTypeOptions != "" {
+				h.Set("X-Content-Type-Options", _sh.ContentTypeOptions)
+			}
+			if _sh.ReferrerPolicy != "" {
+				h.Set("Referrer-Policy", _sh.ReferrerPolicy)
+			}
+			if _sh.PermissionsPolicy != "" {
+				h.Set("Permissions-Policy", _sh.PermissionsPolicy)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// regexp matching opening script and style tags
+var rexpNonceTags = regexp.MustCompile(`(?i)<(script|style)\b[^>]*>`)
+
+// regexp matching the nonce attribute with the CSP_NONCE placeholder value
+var rexpNoncePlaceholder = regexp.MustCompile(`(?i)\bnonce=(["']?)` + regexp.QuoteMeta(CSP_NONCE) + `(["']?)`)
+
+// stampNonce replaces the CSP_NONCE placeholder with the _nonce in the script and style tags of the _html page
+// explicitly marked with it, ie. `<script nonce="{nonce}">`. Other tags are left as is, so inline scripts and styles
+// without the mark are blocked by the CSP.
+func stampNonce(_html []byte, _nonce string) []byte {
+	return rexpNonceTags.ReplaceAllFunc(_html, func(tag []byte) []byte {
+		return rexpNoncePlaceholder.ReplaceAll(tag, []byte("nonce=${1}"+_nonce+"${2}"))
+	})
+}
@@ -0,0 +1,97 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStampNonce(t *testing.T) {
+	tests := []struct {
+		in     string
+		wanted string
+	}{
+		{in: `<script nonce="{nonce}" src="wasm_spa.js"></script>`, wanted: `<script nonce="N" src="wasm_spa.js"></script>`},
+		{in: `<SCRIPT NONCE='{nonce}'>run()</SCRIPT>`, wanted: `<SCRIPT nonce='N'>run()</SCRIPT>`},
+		{in: `<style nonce={nonce}>.a{}</style>`, wanted: `<style nonce=N>.a{}</style>`},
+		{in: `<script src="wasm_spa.js"></script>`, wanted: `<script src="wasm_spa.js"></script>`},
+		{in: `<script>alert(1)</script><style>.a{}</style>`, wanted: `<script>alert(1)</script><style>.a{}</style>`},
+		{in: `<script nonce="X"></script>`, wanted: `<script nonce="X"></script>`},
+		{in: `<div nonce="{nonce}"></div>`, wanted: `<div nonce="{nonce}"></div>`},
+		{in: `<scripts><stylesheet>`, wanted: `<scripts><stylesheet>`},
+		{in: `<div>no tag</div>`, wanted: `<div>no tag</div>`},
+	}
+	for _, tst := range tests {
+		out := string(stampNonce([]byte(tst.in), "N"))
+		if out != tst.wanted {
+			t.Errorf("%q failed. target: %q --> out: %q", tst.in, tst.wanted, out)
+		}
+	}
+}
+
+func TestLoadSecurityHeaders(t *testing.T) {
+	t.Setenv("HTTP_HSTS", "off")
+	t.Setenv("HTTP_REFERRERPOLICY", " no-referrer ")
+	sh := LoadSecurityHeaders()
+	if sh.HSTS != "" {
+		t.Errorf("HSTS must be disabled, got %q", sh.HSTS)
+	}
+	if sh.ReferrerPolicy != "no-referrer" {
+		t.Errorf("unexpected referrer policy %q", sh.ReferrerPolicy)
+	}
+	if sh.ContentTypeOptions != "nosniff" {
+		t.Errorf("default content type options expected, got %q", sh.ContentTypeOptions)
+	}
+}
+
+func TestMiddlewareSecurity(t *testing.T) {
+	ws := WebServer{
+		StaticFS: fstest.MapFS{
+			"index.html": {Data: []byte(`<html><script nonce="{nonce}" src="wasm_spa.js"></script><script>alert(1)</script></html>`)},
+			"app.js":     {Data: []byte(`<script>`)},
+		},
+	}
+	handler := middlewareSecurity(DefaultSecurityHeaders())(ws.staticHandler())
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	csp := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'wasm-unsafe-eval'") || strings.Contains(csp, "unsafe-inline") || !strings.HasSuffix(csp, "frame-ancestors 'none'") {
+		t.Errorf("unexpected CSP %q", csp)
+	}
+	if rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Errorf("HSTS must not be sent without TLS")
+	}
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("X-Content-Type-Options missing")
+	}
+
+	// the nonce stamped in the page must match the one of the CSP
+	body, _ := io.ReadAll(rec.Body)
+	_, after, _ := strings.Cut(string(body), `nonce="`)
+	nonce, _, _ := strings.Cut(after, `"`)
+	if nonce == "" || !strings.Contains(csp, "'nonce-"+nonce+"'") {
+		t.Errorf("nonce %q not found in the CSP %q", nonce, csp)
+	}
+	if !strings.Contains(string(body), `<script>alert(1)</script>`) {
+		t.Errorf("unmarked inline script must not be stamped, got %q", string(body))
+	}
+
+	// a new nonce per request
+	rec2 := httptest.NewRecorder()
+	handler.ServeHTTP(rec2, httptest.NewRequest(http.MethodGet, "/index.html", nil))
+	if rec2.Header().Get("Content-Security-Policy") == csp {
+		t.Errorf("nonce must change with every request")
+	}
+
+	// non html files are served as is
+	rec3 := httptest.NewRecorder()
+	handler.ServeHTTP(rec3, httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	if body, _ := io.ReadAll(rec3.Body); string(body) != `<script>` {
+		t.Errorf("js file must not be stamped, got %q", string(body))
+	}
+}
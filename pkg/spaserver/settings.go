@@ -0,0 +1,205 @@
+package spaserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setting describes an env variable read by the spa server
+type setting struct {
+	name  string
+	check func(value string) error // checks the format of the value, nil if any value is valid
+}
+
+// settings lists every env variables read by the spa server
+var settings = []setting{
+	{"SPA_STATICFILEDIR", nil},
+	{"SPA_WEBSOCKET_PATH", checkPathOrOff},
+	{"SPA_SSE_PATH", checkPathOrOff},
+	{"SPA_CONFIG_PREFIX", nil},
+	{"SPA_PROXY", nil},
+	{"SPA_PROXY_HEADERS", nil},
+	{"HTTP_PORT", checkAddress},
+	{"HTTP_RWTIMEOUT", checkPositiveInt},
+	{"HTTP_IDLETIMEOUT", checkPositiveInt},
+	{"HTTP_CACHE_CONTROL", checkBool},
+	{"HTTP_LOGGER", checkBool},
+	{"HTTP_TLS_CERTFILE", checkFile},
+	{"HTTP_TLS_KEYFILE", checkFile},
+	{"HTTP_CSP", nil},
+	{"HTTP_CSP_FRAMEANCESTORS", nil},
+	{"HTTP_HSTS", nil},
+	{"HTTP_CONTENTTYPEOPTIONS", nil},
+	{"HTTP_REFERRERPOLICY", nil},
+	{"HTTP_PERMISSIONSPOLICY", nil},
+	{"API_CORS_ORIGINS", nil},
+	{"API_CORS_METHODS", nil},
+	{"API_CORS_HEADERS", nil},
+	{"API_CORS_CREDENTIALS", checkBool},
+	{"API_RATELIMIT", checkPositiveFloat},
+	{"API_RATELIMIT_BURST", checkPositiveInt},
+	{"API_MAXBODYSIZE", checkPositiveInt},
+	{"API_MOCK", checkOneOf("off", "replay", "record")},
+	{"API_MOCK_DIR", nil},
+	{"API_MOCK_UPSTREAM", checkURL},
+}
+
+// settings_prefixes are the prefixes of the spa server settings, used to report unknown settings
+var settings_prefixes = []string{"SPA_", "HTTP_", "API_"}
+
+// ValidateSettings checks the format of every spa server settings set in the environment,
+// and reports the _defined variables looking like spa server settings but unknown, ie. with a typo.
+//
+// _defined are usually the names of the variables loaded from env files. Variables starting with
+// the SPA_CONFIG_PREFIX are app config values, not settings.
+//
+// Returns all the errors found, joined.
+func ValidateSettings(_defined []string) error {
+	errs := make([]error, 0)
+
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.name] = true
+		// empty values stand for the default ones
+		value := strings.Trim(os.Getenv(s.name), " ")
+		if value == "" || s.check == nil {
+			continue
+		}
+		if err := s.check(value); err != nil {
+			errs = append(errs, fmt.Errorf("setting %s=%q: %w", s.name, value, err))
+		}
+	}
+	if _, err := LoadProxyRoutes(); err != nil {
+		errs = append(errs, err)
+	}
+
+	configprefix := strings.Trim(os.Getenv("SPA_CONFIG_PREFIX"), " ")
+	if configprefix == "" {
+		configprefix = "SPA_PUBLIC_"
+	}
+	unknown := make([]string, 0)
+	for _, name := range _defined {
+		if known[name] || strings.HasPrefix(name, configprefix) {
+			continue
+		}
+		for _, prefix := range settings_prefixes {
+			if strings.HasPrefix(name, prefix) {
+				unknown = append(unknown, name)
+				break
+			}
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		if guess := closestSetting(name); guess != "" {
+			errs = append(errs, fmt.Errorf("unknown setting %s, did you mean %s?", name, guess))
+		} else {
+			errs = append(errs, fmt.Errorf("unknown setting %s", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closestSetting returns the name of the known setting the closest to _name, or an empty string if none is close enough
+func closestSetting(_name string) string {
+	best, bestdist := "", 3
+	for _, s := range settings {
+		if d := distance(_name, s.name); d < bestdist {
+			best, bestdist = s.name, d
+		}
+	}
+	return best
+}
+
+// distance returns the Levenshtein distance between _a and _b
+func distance(_a string, _b string) int {
+	prev := make([]int, len(_b)+1)
+	curr := make([]int, len(_b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(_a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(_b); j++ {
+			cost := 1
+			if _a[i-1] == _b[j-1] {
+				cost = 0
+			}
+			curr[j] = prev[j-1] + cost
+			if prev[j]+1 < curr[j] {
+				curr[j] = prev[j] + 1
+			}
+			if curr[j-1]+1 < curr[j] {
+				curr[j] = curr[j-1] + 1
+			}
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(_b)]
+}
+
+func checkBool(_value string) error {
+	if v := strings.ToLower(_value); v != "true" && v != "false" {
+		return fmt.Errorf("true or false expected")
+	}
+	return nil
+}
+
+func checkPositiveInt(_value string) error {
+	if i, err := strconv.Atoi(_value); err != nil || i < 0 {
+		return fmt.Errorf("positive integer expected")
+	}
+	return nil
+}
+
+func checkPositiveFloat(_value string) error {
+	if f, err := strconv.ParseFloat(_value, 64); err != nil || f < 0 {
+		return fmt.Errorf("positive number expected")
+	}
+	return nil
+}
+
+func checkAddress(_value string) error {
+	if _, port, err := net.SplitHostPort(_value); err != nil || port == "" {
+		return fmt.Errorf("address with a port expected, ie. \":5500\"")
+	}
+	return nil
+}
+
+func checkPathOrOff(_value string) error {
+	if strings.ToLower(_value) != "off" && !strings.HasPrefix(_value, "/") {
+		return fmt.Errorf("path starting with / or \"off\" expected")
+	}
+	return nil
+}
+
+func checkFile(_value string) error {
+	if _, err := os.Stat(_value); err != nil {
+		return fmt.Errorf("file not found")
+	}
+	return nil
+}
+
+func checkURL(_value string) error {
+	if u, err := url.Parse(_value); err != nil || u.Scheme == "" || u.Host == "" {
+		return fmt.Errorf("absolute url expected, ie. \"http://localhost:8080\"")
+	}
+	return nil
+}
+
+func checkOneOf(_values ...string) func(string) error {
+	return func(_value string) error {
+		for _, v := range _values {
+			if strings.ToLower(_value) == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("one of %s expected", strings.Join(_values, ", "))
+	}
+}
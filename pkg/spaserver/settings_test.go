@@ -0,0 +1,41 @@
+package spaserver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSettings(t *testing.T) {
+	t.Setenv("HTTP_PORT", "5500")
+	t.Setenv("HTTP_LOGGER", "yes")
+	t.Setenv("HTTP_RWTIMEOUT", "")
+	t.Setenv("API_MOCK", "Replay")
+
+	err := ValidateSettings([]string{"HTTP_PORT", "HTTP_LOGER", "SPA_PUBLIC_NAME", "API_FOO", "MY_OWN_SETTING"})
+	if err == nil {
+		t.Fatal("errors expected")
+	}
+	msg := err.Error()
+	expected := []string{
+		`setting HTTP_PORT="5500": address with a port expected`,
+		`setting HTTP_LOGGER="yes": true or false expected`,
+		`unknown setting HTTP_LOGER, did you mean HTTP_LOGGER?`,
+		`unknown setting API_FOO` + "\n",
+	}
+	for _, e := range expected {
+		if !strings.Contains(msg+"\n", e) {
+			t.Errorf("error %q expected in:\n%s", e, msg)
+		}
+	}
+	for _, unexpected := range []string{"HTTP_RWTIMEOUT", "API_MOCK", "SPA_PUBLIC_NAME", "MY_OWN_SETTING"} {
+		if strings.Contains(msg, unexpected) {
+			t.Errorf("unexpected error on %s:\n%s", unexpected, msg)
+		}
+	}
+
+	t.Setenv("HTTP_PORT", ":5500")
+	t.Setenv("HTTP_LOGGER", "TRUE")
+	if err := ValidateSettings(nil); err != nil {
+		t.Errorf("valid settings expected, got %s", err)
+	}
+}
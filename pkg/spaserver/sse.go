@@ -0,0 +1,192 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sse_heartbeat         = 15 * time.Second // period of the comment lines keeping the stream alive through proxies
+	sse_clientBufferSize  = 64               // number of outgoing events buffered per client
+	sse_defaultBufferSize = 100              // default number of events kept for Last-Event-ID replay
+)
+
+// SSEvent is a single server-sent event.
+type SSEvent struct {
+	Id    uint64 // the event id, set by the broadcaster, sent to the client and returned within the Last-Event-ID header on reconnection
+	Event string // the optional event name, "message" for the client when empty
+	Data  string // the event data, can be multiline
+}
+
+// write formats the event according to the text/event-stream format.
+//
+// https://html.spec.whatwg.org/multipage/server-sent-events.html#event-stream-interpretation
+func (_evt SSEvent) write(w *strings.Builder) {
+	fmt.Fprintf(w, "id: %d\n", _evt.Id)
+	if _evt.Event != "" {
+		fmt.Fprintf(w, "event: %s\n", _evt.Event)
+	}
+	for _, line := range strings.Split(_evt.Data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	w.WriteString("\n")
+}
+
+// SSEBroadcaster streams server-sent events to every connected client.
+// Published events are kept in a bounded buffer to be replayed to clients
+// reconnecting with a Last-Event-ID header.
+//
+// SSEBroadcaster is an http.Handler serving the text/event-stream.
+type SSEBroadcaster struct {
+	mu      sync.RWMutex
+	lastid  uint64
+	buffer  []SSEvent // the last published events, oldest first
+	bufsize int
+	clients map[chan SSEvent]struct{}
+}
+
+// NewSSEBroadcaster is the SSEBroadcaster factory.
+// _bufsize is the number of events kept for replay, 100 by default if _bufsize <= 0.
+//
+// Event ids start from the current time in microseconds, so the ids of a restarted server are greater than the
+// Last-Event-ID of its previous clients, which get the buffered events replayed.
+func NewSSEBroadcaster(_bufsize int) *SSEBroadcaster {
+	if _bufsize <= 0 {
+		_bufsize = sse_defaultBufferSize
+	}
+	b := new(SSEBroadcaster)
+	b.bufsize = _bufsize
+	b.lastid = uint64(time.Now().UnixMicro())
+	b.buffer = make([]SSEvent, 0, _bufsize)
+	b.clients = make(map[chan SSEvent]struct{})
+	return b
+}
+
+// Publish sends an event to every connected client and returns its id.
+// _data is sent as is if it's a string, otherwise it's encoded in json.
+// An empty _event name is received as a "message" event by the client.
+func (b *SSEBroadcaster) Publish(_event string, _data any) (_id uint64, _err error) {
+	if strings.ContainsAny(_event, "\r\n") {
+		return 0, fmt.Errorf("sse publish failed: invalid event name %q", _event)
+	}
+	var data string
+	switch v := _data.(type) {
+	case string:
+		data = v
+	default:
+		bdata, err := json.Marshal(_data)
+		if err != nil {
+			return 0, fmt.Errorf("sse publish %q failed: %w", _event, err)
+		}
+		data = string(bdata)
+	}
+	data = strings.ReplaceAll(strings.ReplaceAll(data, "\r\n", "\n"), "\r", "\n")
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastid++
+	evt := SSEvent{Id: b.lastid, Event: _event, Data: data}
+	if len(b.buffer) == b.bufsize {
+		copy(b.buffer, b.buffer[1:])
+		b.buffer = b.buffer[:b.bufsize-1]
+	}
+	b.buffer = append(b.buffer, evt)
+
+	for client := range b.clients {
+		select {
+		case client <- evt:
+		default:
+			// slow client, it will get the missed events on reconnection with the Last-Event-ID
+			delete(b.clients, client)
+			close(client)
+		}
+	}
+	return evt.Id, nil
+}
+
+// ClientsCount returns the number of connected clients.
+func (b *SSEBroadcaster) ClientsCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.clients)
+}
+
+// ServeHTTP streams events to the client until it disconnects.
+// The events buffered after the Last-Event-ID request header are sent first.
+func (b *SSEBroadcaster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// the stream lasts longer than the server write timeout
+	rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	client := make(chan SSEvent, sse_clientBufferSize)
+	lastid, _ := strconv.ParseUint(strings.Trim(r.Header.Get("Last-Event-ID"), " "), 10, 64)
+
+	// register the client and get the events to replay in a single lock, so no event is missed
+	b.mu.Lock()
+	replay := make([]SSEvent, 0)
+	for _, evt := range b.buffer {
+		if evt.Id > lastid {
+			replay = append(replay, evt)
+		}
+	}
+	b.clients[client] = struct{}{}
+	b.mu.Unlock()
+
+	defer func() {
+		b.mu.Lock()
+		if _, found := b.clients[client]; found {
+			delete(b.clients, client)
+			close(client)
+		}
+		b.mu.Unlock()
+	}()
+
+	var out strings.Builder
+	for _, evt := range replay {
+		evt.write(&out)
+	}
+	out.WriteString(": connected\n\n")
+	if _, err := w.Write([]byte(out.String())); err != nil {
+		return
+	}
+	if err := rc.Flush(); err != nil {
+		log.Println("sse streaming unsupported:", err)
+		return
+	}
+
+	heartbeat := time.NewTicker(sse_heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		out.Reset()
+		select {
+		case <-r.Context().Done():
+			return
+		case evt, ok := <-client:
+			if !ok {
+				return
+			}
+			evt.write(&out)
+		case <-heartbeat.C:
+			out.WriteString(": ping\n\n")
+		}
+		if _, err := w.Write([]byte(out.String())); err != nil {
+			return
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package spaserver
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readSSEvents reads n events from the stream, ignoring comments
+func readSSEvents(t *testing.T, rd *bufio.Reader, n int) []string {
+	events := make([]string, 0, n)
+	var evt strings.Builder
+	for len(events) < n {
+		line, err := rd.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reading stream failed: %s", err)
+		}
+		switch {
+		case line == "\n":
+			if evt.Len() > 0 {
+				events = append(events, evt.String())
+				evt.Reset()
+			}
+		case strings.HasPrefix(line, ":"):
+		default:
+			evt.WriteString(line)
+		}
+	}
+	return events
+}
+
+func TestSSEBroadcaster(t *testing.T) {
+	b := NewSSEBroadcaster(2)
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	first, _ := b.Publish("", "one")                // out of the buffer
+	b.Publish("notify", "two\nlines")               // first+1
+	b.Publish("notify", map[string]int{"three": 3}) // first+2
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", "0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("wrong content-type %q", ct)
+	}
+
+	rd := bufio.NewReader(resp.Body)
+	replay := readSSEvents(t, rd, 2)
+	if replay[0] != fmt.Sprintf("id: %d\nevent: notify\ndata: two\ndata: lines\n", first+1) {
+		t.Errorf("unexpected replayed event %q", replay[0])
+	}
+	if replay[1] != fmt.Sprintf("id: %d\nevent: notify\ndata: {\"three\":3}\n", first+2) {
+		t.Errorf("unexpected replayed event %q", replay[1])
+	}
+
+	for i := 0; i < 100 && b.ClientsCount() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	b.Publish("", "live")
+	live := readSSEvents(t, rd, 1)
+	if live[0] != fmt.Sprintf("id: %d\ndata: live\n", first+3) {
+		t.Errorf("unexpected live event %q", live[0])
+	}
+}
+
+func TestSSEBroadcasterLastEventID(t *testing.T) {
+	b := NewSSEBroadcaster(10)
+	srv := httptest.NewServer(b)
+	defer srv.Close()
+
+	ids := make([]uint64, 3)
+	for i := range ids {
+		ids[i], _ = b.Publish("", "data")
+	}
+	if _, err := b.Publish("bad\nname", "data"); err == nil {
+		t.Errorf("invalid event name must fail")
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Last-Event-ID", strconv.FormatUint(ids[1], 10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	replay := readSSEvents(t, bufio.NewReader(resp.Body), 1)
+	if !strings.HasPrefix(replay[0], fmt.Sprintf("id: %d\n", ids[2])) {
+		t.Errorf("only the last event expected to be replayed, got %q", replay[0])
+	}
+
+	// the ids of a restarted server follow the previous ones
+	time.Sleep(time.Millisecond)
+	if id, _ := NewSSEBroadcaster(10).Publish("", "data"); id <= ids[2] {
+		t.Errorf("id %d of a new broadcaster expected greater than %d", id, ids[2])
+	}
+}
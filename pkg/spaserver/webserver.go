@@ -5,10 +5,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path"
 	"strconv"
 	"strings"
 	"syscall"
@@ -24,9 +26,41 @@ type WebServer struct {
 	http_idleTimeout   int
 	http_cache_control bool
 	http_logger        bool
+	http_tls_certfile  string
+	http_tls_keyfile   string
+	websocket_path     string
+	sse_path           string
+	config_prefix      string
+	api_mock           string
+	api_mock_dir       string
+	api_mock_upstream  string
 
 	WebRouter *mux.Router
 	ApiRouter *mux.Router
+	WSHub     *WSHub          // the websocket pub/sub hub, nil if SPA_WEBSOCKET_PATH is "off"
+	SSE       *SSEBroadcaster // the server-sent events broadcaster, nil if SPA_SSE_PATH is "off"
+
+	// StaticFS is the optional file system of the spa static files, ie. an embed.FS compiled into the binary.
+	// If nil, files are served from the SPA_STATICFILEDIR directory.
+	StaticFS fs.FS
+
+	// Security holds the security headers added to every response, loaded from the env variables.
+	Security SecurityHeaders
+
+	// AppConfig is the configuration injected into the index.html pages, decoded by the wasm app with ick.App.Config.
+	// It's loaded from the env variables starting with SPA_CONFIG_PREFIX, and can be completed before Run.
+	AppConfig map[string]any
+
+	// Proxies are the reverse proxy routes forwarding requests to upstream servers, loaded from the SPA_PROXY env variable.
+	// Routes can be added before Run, they take precedence over the static files but not over the ApiRouter routes.
+	Proxies []ProxyRoute
+
+	// Api holds the CORS, rate limiting and body size protections of the /api subrouter, loaded from the env variables.
+	Api ApiConfig
+
+	// ExportComponents are the server side renderers of the components by <ick-name> tag, rendering them into
+	// the pages written by Export. The components without a renderer are left to the wasm app.
+	ExportComponents map[string]ExportComponent
 }
 
 func MakeWebserver() WebServer {
@@ -63,29 +97,111 @@ func MakeWebserver() WebServer {
 		ws.http_logger = true
 	}
 
+	ws.http_tls_certfile = strings.Trim(os.Getenv("HTTP_TLS_CERTFILE"), " ")
+	ws.http_tls_keyfile = strings.Trim(os.Getenv("HTTP_TLS_KEYFILE"), " ")
+
+	ws.Security = LoadSecurityHeaders()
+	ws.Api = LoadApiConfig()
+
+	// route paths are case sensitive
+	ws.websocket_path = strings.Trim(os.Getenv("SPA_WEBSOCKET_PATH"), " ")
+	if ws.websocket_path == "" {
+		ws.websocket_path = "/ws"
+	} else if strings.ToLower(ws.websocket_path) == "off" {
+		ws.websocket_path = "off"
+	}
+
+	ws.sse_path = strings.Trim(os.Getenv("SPA_SSE_PATH"), " ")
+	if ws.sse_path == "" {
+		ws.sse_path = "/sse"
+	} else if strings.ToLower(ws.sse_path) == "off" {
+		ws.sse_path = "off"
+	}
+
+	ws.config_prefix = strings.Trim(os.Getenv("SPA_CONFIG_PREFIX"), " ")
+	if ws.config_prefix == "" {
+		ws.config_prefix = "SPA_PUBLIC_"
+	} else if strings.ToLower(ws.config_prefix) == "off" {
+		ws.config_prefix = ""
+	}
+	ws.AppConfig = LoadAppConfig(ws.config_prefix)
+
+	ws.api_mock = strings.ToLower(strings.Trim(os.Getenv("API_MOCK"), " "))
+	if ws.api_mock == "" {
+		ws.api_mock = "off"
+	}
+	ws.api_mock_dir = strings.Trim(os.Getenv("API_MOCK_DIR"), " ")
+	if ws.api_mock_dir == "" {
+		ws.api_mock_dir = "./mocks"
+	}
+	ws.api_mock_upstream = strings.Trim(os.Getenv("API_MOCK_UPSTREAM"), " ")
+
+	var err error
+	if ws.Proxies, err = LoadProxyRoutes(); err != nil {
+		log.Fatalf("spa server: %s", err)
+	}
+
 	// configure the server, with or without trailing slash is the same route
 	ws.WebRouter = mux.NewRouter().StrictSlash(true)
 
 	// configure the /api subrouter
 	ws.ApiRouter = ws.WebRouter.PathPrefix("/api").Subrouter()
+	ws.mockApi()
 	ws.ApiRouter.HandleFunc("/health", GetHealthHandle())
 
+	// configure the websocket hub
+	if ws.websocket_path != "off" {
+		ws.WSHub = NewWSHub()
+		ws.WebRouter.Handle(ws.websocket_path, ws.WSHub)
+	}
+
+	// configure the server-sent events broadcaster
+	if ws.sse_path != "off" {
+		ws.SSE = NewSSEBroadcaster(0)
+		ws.WebRouter.Handle(ws.sse_path, ws.SSE).Methods(http.MethodGet)
+	}
+
 	return *ws
 }
 
 func (ws WebServer) Run() {
 
 	// let's go
-	fmt.Printf("Starting the SPA serving assets from %q and /api on port %s\n", ws.staticfiledir, ws.http_port)
+	if ws.StaticFS != nil {
+		fmt.Printf("Starting the SPA serving embedded assets and /api on port %s\n", ws.http_port)
+	} else {
+		fmt.Printf("Starting the SPA serving assets from %q and /api on port %s\n", ws.staticfiledir, ws.http_port)
+	}
 
-	// the main handler serving spa static files
-	// force content-type header for wasm files
-	ws.WebRouter.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if strings.HasSuffix(r.URL.Path, ".wasm") {
-			w.Header().Set("content-type", "application/wasm")
+	// the reverse proxy routes
+	for _, route := range ws.Proxies {
+		handler, err := route.handler(time.Duration(ws.http_rwTimeout) * time.Second)
+		if err != nil {
+			log.Fatalf("spa server: %s", err)
 		}
-		http.FileServer(http.Dir(ws.staticfiledir)).ServeHTTP(w, r)
-	})
+		ws.WebRouter.MatcherFunc(route.match).Handler(handler)
+		fmt.Printf("spa server: %s proxied to %q\n", route.Prefix, route.Upstream)
+	}
+
+	// the main handler serving spa static files
+	ws.WebRouter.PathPrefix("/").Handler(ws.staticHandler())
+
+	if ws.WSHub != nil {
+		fmt.Printf("spa server: websocket hub listening on %q\n", ws.websocket_path)
+	}
+	if ws.SSE != nil {
+		fmt.Printf("spa server: server-sent events streamed on %q\n", ws.sse_path)
+	}
+	if ws.config_prefix != "" {
+		fmt.Printf("spa server: %d app config values injected from %s* env variables\n", len(ws.AppConfig), ws.config_prefix)
+	}
+
+	// protect the /api subrouter
+	ws.protectApi()
+
+	// add middleware to set security headers
+	fmt.Println("spa server: security headers are on")
+	ws.WebRouter.Use(middlewareSecurity(ws.Security))
 
 	// add middleware to remove cache if requested in config file
 	if !ws.http_cache_control {
@@ -111,7 +227,14 @@ func (ws WebServer) Run() {
 
 	// listen and serve in a go routine to allow catching shutdown clean request in parallel
 	go func() {
-		if err := srv.ListenAndServe(); err != nil {
+		var err error
+		if ws.http_tls_certfile != "" && ws.http_tls_keyfile != "" {
+			fmt.Println("spa server: TLS is on")
+			err = srv.ListenAndServeTLS(ws.http_tls_certfile, ws.http_tls_keyfile)
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil {
 			log.Println(err)
 		}
 	}()
@@ -139,6 +262,106 @@ func (ws WebServer) Run() {
 	fmt.Println("SPA web Server is down")
 }
 
+// mockApi configures the /api subrouter according to the API_MOCK mode:
+// "replay" serves the fixtures of the API_MOCK_DIR directory before any other api route,
+// "record" forwards every api requests to API_MOCK_UPSTREAM and saves the responses as fixtures.
+func (ws WebServer) mockApi() {
+	switch ws.api_mock {
+	case "off":
+	case "replay":
+		fixtures, err := LoadMockFixtures(ws.api_mock_dir)
+		if err != nil {
+			log.Fatalf("spa server: %s", err)
+		}
+		RegisterMockFixtures(ws.ApiRouter, fixtures)
+		fmt.Printf("spa server: api mocked with %d fixtures from %q\n", len(fixtures), ws.api_mock_dir)
+	case "record":
+		recorder, err := NewMockRecorder(ws.api_mock_upstream, ws.api_mock_dir, "/api")
+		if err != nil {
+			log.Fatalf("spa server: %s", err)
+		}
+		ws.ApiRouter.PathPrefix("/").Handler(recorder)
+		fmt.Printf("spa server: api forwarded to %q and recorded into %q\n", ws.api_mock_upstream, ws.api_mock_dir)
+	default:
+		log.Fatalf("spa server: invalid API_MOCK mode %q, must be off, replay or record", ws.api_mock)
+	}
+}
+
+// protectApi adds the recovery, CORS, rate limiting and body size middlewares to the /api subrouter.
+func (ws WebServer) protectApi() {
+	ws.ApiRouter.Use(middlewareRecover)
+	if len(ws.Api.CORSOrigins) > 0 {
+		fmt.Printf("spa server: api CORS allowed for %s\n", strings.Join(ws.Api.CORSOrigins, ", "))
+		ws.ApiRouter.Use(middlewareCORS(ws.Api))
+		// preflight requests must reach the CORS middleware even for routes restricted to other methods
+		ws.ApiRouter.Methods(http.MethodOptions).HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	if ws.Api.RateLimit > 0 {
+		fmt.Printf("spa server: api rate limited to %v requests/s per client, burst %d\n", ws.Api.RateLimit, ws.Api.RateBurst)
+		ws.ApiRouter.Use(middlewareRateLimit(ws.Api.RateLimit, ws.Api.RateBurst))
+	}
+	if ws.Api.MaxBodySize > 0 {
+		ws.ApiRouter.Use(middlewareMaxBodySize(ws.Api.MaxBodySize))
+	}
+}
+
+// staticHandler returns the handler serving spa static files, either from StaticFS or from the static file directory.
+// It forces the content-type header for wasm files, injects the AppConfig into index.html pages, and stamps the CSP nonce into html pages.
+func (ws WebServer) staticHandler() http.Handler {
+	fsys := ws.StaticFS
+	if fsys == nil {
+		fsys = os.DirFS(ws.staticfiledir)
+	}
+	fileserver := http.FileServer(http.FS(fsys))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".wasm") {
+			w.Header().Set("content-type", "application/wasm")
+		}
+		if serveHTML(fsys, w, r, Nonce(r), ws.AppConfig) {
+			return
+		}
+		fileserver.ServeHTTP(w, r)
+	})
+}
+
+// serveHTML serves the html page requested by r, stamped with the _nonce if any.
+// The _config is injected into index.html pages.
+// Returns false if the request does not target an html page, or if the page is not found.
+func serveHTML(_fsys fs.FS, w http.ResponseWriter, r *http.Request, _nonce string, _config map[string]any) bool {
+	name := r.URL.Path
+	switch {
+	case strings.HasSuffix(name, "/"):
+		name += "index.html"
+	case strings.HasSuffix(name, ".html"):
+	default:
+		return false
+	}
+	name = strings.TrimPrefix(path.Clean(name), "/")
+
+	html, err := fs.ReadFile(_fsys, name)
+	if err != nil {
+		return false
+	}
+	if path.Base(name) == "index.html" {
+		html = injectAppConfig(html, _config)
+	}
+	if _nonce != "" {
+		html = stampNonce(html, _nonce)
+	}
+
+	// the nonce and the config can change with every request so the page must not be reused
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Length", strconv.Itoa(len(html)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(html)
+	}
+	return true
+}
+
 // GetHealthHandle responds to a GET Health api request
 func GetHealthHandle() func(http.ResponseWriter, *http.Request) {
 	counter := 0
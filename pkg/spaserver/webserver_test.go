@@ -0,0 +1,98 @@
+package spaserver
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+)
+
+func TestStaticFS(t *testing.T) {
+	ws := WebServer{
+		StaticFS: fstest.MapFS{
+			"index.html": {Data: []byte("<html>index</html>")},
+			"spa.wasm":   {Data: []byte("\x00asm")},
+		},
+	}
+	handler := ws.staticHandler()
+
+	tests := []struct {
+		path        string
+		status      int
+		contentType string
+		body        string
+	}{
+		{path: "/", status: http.StatusOK, contentType: "text/html; charset=utf-8", body: "<html>index</html>"},
+		{path: "/spa.wasm", status: http.StatusOK, contentType: "application/wasm", body: "\x00asm"},
+		{path: "/missing.js", status: http.StatusNotFound},
+	}
+
+	for _, tst := range tests {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tst.path, nil))
+		if rec.Code != tst.status {
+			t.Errorf("%s: status %v expected, got %v", tst.path, tst.status, rec.Code)
+			continue
+		}
+		if tst.status != http.StatusOK {
+			continue
+		}
+		if ct := rec.Header().Get("content-type"); ct != tst.contentType {
+			t.Errorf("%s: content-type %q expected, got %q", tst.path, tst.contentType, ct)
+		}
+		if body, _ := io.ReadAll(rec.Body); string(body) != tst.body {
+			t.Errorf("%s: unexpected body %q", tst.path, string(body))
+		}
+	}
+}
+
+func TestAppConfig(t *testing.T) {
+	t.Setenv("TEST_PUBLIC_API_URL", "https://api.example.com")
+	t.Setenv("TEST_PUBLIC_BANNER", "</script><script>alert(1)</script>")
+	t.Setenv("TEST_SECRET", "secret")
+
+	ws := WebServer{
+		StaticFS: fstest.MapFS{
+			"index.html": {Data: []byte(`<script id="ick-config" type="application/json"></script>`)},
+			"other.html": {Data: []byte(`<script id="ick-config" type="application/json"></script>`)},
+		},
+		AppConfig: LoadAppConfig("TEST_PUBLIC_"),
+	}
+	handler := ws.staticHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	body, _ := io.ReadAll(rec.Body)
+	expected := `<script id="ick-config" type="application/json">{"API_URL":"https://api.example.com","BANNER":"\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script>`
+	if string(body) != expected {
+		t.Errorf("unexpected index.html %q", string(body))
+	}
+
+	// the config is injected into index.html pages only
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other.html", nil))
+	if body, _ := io.ReadAll(rec.Body); string(body) != `<script id="ick-config" type="application/json"></script>` {
+		t.Errorf("unexpected other.html %q", string(body))
+	}
+}
+
+func TestInjectAppConfig(t *testing.T) {
+	config := map[string]any{"A": "a"}
+	tests := []struct {
+		html     string
+		config   map[string]any
+		expected string
+	}{
+		{html: `<script type="application/json" id='ick-config'>{{ .Config }}</script>`, config: config, expected: `<script type="application/json" id='ick-config'>{"A":"a"}</script>`},
+		{html: `<script id="ick-config"></script>`, config: nil, expected: `<script id="ick-config">{}</script>`},
+		{html: `<head><title>{{ code }}</title></HEAD><body>`, config: config, expected: `<head><title>{{ code }}</title><script id="ick-config" type="application/json">{"A":"a"}</script></HEAD><body>`},
+		{html: `<head></head>{{ code }}`, config: nil, expected: `<head></head>{{ code }}`},
+		{html: `<p>no head</p>`, config: config, expected: `<p>no head</p>`},
+	}
+	for _, tst := range tests {
+		if out := string(injectAppConfig([]byte(tst.html), tst.config)); out != tst.expected {
+			t.Errorf("%q: %q expected, got %q", tst.html, tst.expected, out)
+		}
+	}
+}
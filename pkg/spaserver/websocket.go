@@ -0,0 +1,278 @@
+package spaserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// types of WSMessage envelopes exchanged between the hub and its clients
+const (
+	WSMSG_SUBSCRIBE   string = "subscribe"   // client -> hub: start receiving messages published on Topic
+	WSMSG_UNSUBSCRIBE string = "unsubscribe" // client -> hub: stop receiving messages published on Topic
+	WSMSG_PUBLISH     string = "publish"     // client -> hub: publish Data on Topic
+	WSMSG_MESSAGE     string = "message"     // hub -> client: Data has been published on Topic
+	WSMSG_ERROR       string = "error"       // hub -> client: the last client request failed, Data holds the reason
+)
+
+const (
+	ws_writeWait      = 10 * time.Second     // time allowed to write a message to the peer
+	ws_pongWait       = 60 * time.Second     // time allowed to read the next pong message from the peer
+	ws_pingPeriod     = ws_pongWait * 9 / 10 // send pings to peer with this period, must be less than pongWait
+	ws_maxMessageSize = 64 * 1024            // maximum message size allowed from peer
+	ws_sendBufferSize = 256                  // number of outgoing messages buffered per client
+)
+
+// WSMessage is the JSON envelope of every message exchanged over the websocket.
+type WSMessage struct {
+	Type  string          `json:"type"`
+	Topic string          `json:"topic,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+// WSHub maintains the set of active websocket clients and dispatches
+// messages published on a topic to every client subscribed to it.
+//
+// WSHub is an http.Handler upgrading incoming requests to websocket connections.
+type WSHub struct {
+	// ClientPublish allows clients to publish messages to the other subscribers of a topic.
+	// false by default, only the server can publish.
+	ClientPublish bool
+
+	// OnMessage is an optional handler called for every WSMSG_PUBLISH message received from a client,
+	// whatever the ClientPublish value.
+	OnMessage func(msg WSMessage)
+
+	upgrader websocket.Upgrader
+
+	mu      sync.RWMutex
+	clients map[*wsClient]struct{}
+	topics  map[string]map[*wsClient]struct{}
+}
+
+// NewWSHub is the WSHub factory.
+func NewWSHub() *WSHub {
+	hub := new(WSHub)
+	hub.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+	hub.clients = make(map[*wsClient]struct{})
+	hub.topics = make(map[string]map[*wsClient]struct{})
+	return hub
+}
+
+// Publish sends _data, encoded in json, to every client subscribed to _topic.
+func (hub *WSHub) Publish(_topic string, _data any) error {
+	data, err := json.Marshal(_data)
+	if err != nil {
+		return fmt.Errorf("websocket publish %q failed: %w", _topic, err)
+	}
+	hub.broadcast(WSMessage{Type: WSMSG_MESSAGE, Topic: _topic, Data: data})
+	return nil
+}
+
+// ClientsCount returns the number of connected clients.
+func (hub *WSHub) ClientsCount() int {
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	return len(hub.clients)
+}
+
+// ServeHTTP upgrades the request to a websocket connection and registers the new client.
+func (hub *WSHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := hub.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// the upgrader has already replied with an http error
+		log.Println("websocket upgrade failed:", err)
+		return
+	}
+
+	client := &wsClient{
+		hub:    hub,
+		conn:   conn,
+		send:   make(chan []byte, ws_sendBufferSize),
+		topics: make(map[string]struct{}),
+	}
+	hub.mu.Lock()
+	hub.clients[client] = struct{}{}
+	hub.mu.Unlock()
+
+	go client.writePump()
+	go client.readPump()
+}
+
+// broadcast queues msg for every client subscribed to msg.Topic.
+// Slow clients whose send buffer is full are disconnected.
+func (hub *WSHub) broadcast(msg WSMessage) {
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		log.Println("websocket broadcast failed:", err)
+		return
+	}
+
+	hub.mu.RLock()
+	slow := make([]*wsClient, 0)
+	for client := range hub.topics[msg.Topic] {
+		select {
+		case client.send <- payload:
+		default:
+			slow = append(slow, client)
+		}
+	}
+	hub.mu.RUnlock()
+
+	for _, client := range slow {
+		hub.unregister(client)
+	}
+}
+
+func (hub *WSHub) subscribe(_client *wsClient, _topic string) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	subscribers, found := hub.topics[_topic]
+	if !found {
+		subscribers = make(map[*wsClient]struct{})
+		hub.topics[_topic] = subscribers
+	}
+	subscribers[_client] = struct{}{}
+	_client.topics[_topic] = struct{}{}
+}
+
+func (hub *WSHub) unsubscribe(_client *wsClient, _topic string) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	hub.unsubscribeLocked(_client, _topic)
+}
+
+func (hub *WSHub) unsubscribeLocked(_client *wsClient, _topic string) {
+	if subscribers, found := hub.topics[_topic]; found {
+		delete(subscribers, _client)
+		if len(subscribers) == 0 {
+			delete(hub.topics, _topic)
+		}
+	}
+	delete(_client.topics, _topic)
+}
+
+// unregister removes the client from the hub and closes its send channel, which ends its write pump.
+func (hub *WSHub) unregister(_client *wsClient) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+	if _, found := hub.clients[_client]; !found {
+		return
+	}
+	for topic := range _client.topics {
+		hub.unsubscribeLocked(_client, topic)
+	}
+	delete(hub.clients, _client)
+	close(_client.send)
+}
+
+/******************************************************************************
+* wsClient
+******************************************************************************/
+
+// wsClient is a middleman between a websocket connection and the hub.
+type wsClient struct {
+	hub    *WSHub
+	conn   *websocket.Conn
+	send   chan []byte         // buffered channel of outbound messages
+	topics map[string]struct{} // subscribed topics, protected by the hub mutex
+}
+
+// readPump pumps messages from the websocket connection to the hub.
+func (c *wsClient) readPump() {
+	defer func() {
+		c.hub.unregister(c)
+		c.conn.Close()
+	}()
+	c.conn.SetReadLimit(ws_maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(ws_pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.conn.SetReadDeadline(time.Now().Add(ws_pongWait))
+		return nil
+	})
+
+	for {
+		var msg WSMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Println("websocket read failed:", err)
+			}
+			return
+		}
+
+		if msg.Topic == "" {
+			c.reply(WSMSG_ERROR, "", "topic missing")
+			continue
+		}
+
+		switch msg.Type {
+		case WSMSG_SUBSCRIBE:
+			c.hub.subscribe(c, msg.Topic)
+		case WSMSG_UNSUBSCRIBE:
+			c.hub.unsubscribe(c, msg.Topic)
+		case WSMSG_PUBLISH:
+			if c.hub.OnMessage != nil {
+				c.hub.OnMessage(msg)
+			}
+			if c.hub.ClientPublish {
+				c.hub.broadcast(WSMessage{Type: WSMSG_MESSAGE, Topic: msg.Topic, Data: msg.Data})
+			}
+		default:
+			c.reply(WSMSG_ERROR, msg.Topic, fmt.Sprintf("unknown message type %q", msg.Type))
+		}
+	}
+}
+
+// reply queues a message for this client only. The message is dropped if the send buffer is full.
+func (c *wsClient) reply(_type string, _topic string, _data any) {
+	data, _ := json.Marshal(_data)
+	payload, _ := json.Marshal(WSMessage{Type: _type, Topic: _topic, Data: data})
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if _, found := c.hub.clients[c]; !found {
+		return
+	}
+	select {
+	case c.send <- payload:
+	default:
+	}
+}
+
+// writePump pumps messages from the hub to the websocket connection, and keeps the connection alive with pings.
+func (c *wsClient) writePump() {
+	ticker := time.NewTicker(ws_pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case payload, ok := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(ws_writeWait))
+			if !ok {
+				// the hub closed the channel
+				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := c.conn.WriteMessage(websocket.TextMessage, payload); err != nil {
+				return
+			}
+
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(ws_writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package spaserver
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func dialHub(t *testing.T, srv *httptest.Server) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %s", err)
+	}
+	return conn
+}
+
+// waitSubscribers waits until the hub has registered n subscribers on _topic
+func waitSubscribers(t *testing.T, hub *WSHub, _topic string, n int) {
+	for i := 0; i < 100; i++ {
+		hub.mu.RLock()
+		count := len(hub.topics[_topic])
+		hub.mu.RUnlock()
+		if count == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("topic %q: %d subscribers expected", _topic, n)
+}
+
+func TestWSHubPublish(t *testing.T) {
+	hub := NewWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn := dialHub(t, srv)
+	defer conn.Close()
+
+	if err := conn.WriteJSON(WSMessage{Type: WSMSG_SUBSCRIBE, Topic: "news"}); err != nil {
+		t.Fatal(err)
+	}
+	waitSubscribers(t, hub, "news", 1)
+
+	hub.Publish("other", "ignored")
+	if err := hub.Publish("news", map[string]string{"title": "hello"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg WSMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != WSMSG_MESSAGE || msg.Topic != "news" || string(msg.Data) != `{"title":"hello"}` {
+		t.Errorf("unexpected message: %+v %s", msg, string(msg.Data))
+	}
+
+	conn.WriteJSON(WSMessage{Type: WSMSG_UNSUBSCRIBE, Topic: "news"})
+	waitSubscribers(t, hub, "news", 0)
+}
+
+func TestWSHubClientPublish(t *testing.T) {
+	for _, clientPublish := range []bool{false, true} {
+		hub := NewWSHub()
+		hub.ClientPublish = clientPublish
+		received := make(chan WSMessage, 1)
+		hub.OnMessage = func(msg WSMessage) { received <- msg }
+		srv := httptest.NewServer(hub)
+
+		sub := dialHub(t, srv)
+		pub := dialHub(t, srv)
+
+		sub.WriteJSON(WSMessage{Type: WSMSG_SUBSCRIBE, Topic: "chat"})
+		waitSubscribers(t, hub, "chat", 1)
+
+		// client publish is always forwarded to OnMessage, but broadcasted only with ClientPublish
+		pub.WriteJSON(WSMessage{Type: WSMSG_PUBLISH, Topic: "chat", Data: []byte(`"hi"`)})
+		select {
+		case msg := <-received:
+			if string(msg.Data) != `"hi"` {
+				t.Errorf("unexpected OnMessage data %s", string(msg.Data))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("OnMessage not called")
+		}
+
+		sub.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		var msg WSMessage
+		err := sub.ReadJSON(&msg)
+		if clientPublish && (err != nil || string(msg.Data) != `"hi"`) {
+			t.Errorf("broadcasted message expected: %v %s", err, string(msg.Data))
+		}
+		if !clientPublish && err == nil {
+			t.Errorf("no broadcasted message expected, got %+v", msg)
+		}
+
+		sub.Close()
+		pub.Close()
+		srv.Close()
+	}
+}
+
+func TestWSHubUnknownType(t *testing.T) {
+	hub := NewWSHub()
+	srv := httptest.NewServer(hub)
+	defer srv.Close()
+
+	conn := dialHub(t, srv)
+	defer conn.Close()
+
+	conn.WriteJSON(WSMessage{Type: "dummy", Topic: "news"})
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var msg WSMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Type != WSMSG_ERROR {
+		t.Errorf("error message expected, got %+v", msg)
+	}
+}
@@ -1,11 +1,13 @@
 package ui
 
 import (
+	"html"
 	"time"
 
 	_ "embed"
 
 	ick "github.com/sunraylab/icecake/pkg/icecake"
+	"github.com/sunraylab/icecake/pkg/sanitize"
 )
 
 /******************************************************************************
@@ -16,7 +18,7 @@ import (
 var css string
 
 func init() {
-	ick.App.RegisterComponent("ick-notify", Notify{}, css)
+	ick.MustRegister[Notify]("ick-notify", ick.WithScopedCSS(css))
 }
 
 type Notify struct {
@@ -28,8 +30,9 @@ type Notify struct {
 	TickerStep time.Duration // The optional ticker step, 1s by default
 	PopupTime  time.Time     // The last popup time
 
-	// the message to display within the notification
-	Message string
+	// the message to display within the notification, trusted html.
+	// Use sanitize.HTML to display an untrusted message.
+	Message ick.HTML
 
 	// The notification will close automatically after Timeout duration.
 	// The timer starts when the notification pops up.
@@ -49,19 +52,11 @@ func (c *Notify) Container() (_tagname string, _classes string, _attrs string) {
 }
 
 func (c *Notify) Template() (_html string) {
-	return `<button class="delete"></button>` + c.Message
+	return `<button class="delete" ick-on:click="Close"></button>{{.Me.Message}}`
 }
 
 // AddListeners is called by the dispatcher after DOM rendering
 func (c *Notify) AddListeners() {
-
-	btndel := c.SelectorQueryFirst(".delete")
-	btndel.AddMouseEvent(ick.MOUSE_ONCLICK, func(*ick.MouseEvent, *ick.Element) {
-		//errors.ConsoleLogf("Mouse Event Fired on %s id=%q, %s\n", c.TagName(), c.Id(), c.NodeName())
-		c.Stop()
-		c.Remove()
-	})
-
 	if c.Timeout != 0 {
 		if c.TickerStep == 0 {
 			c.TickerStep = 1 * time.Second
@@ -80,13 +75,16 @@ func (c *Notify) AddListeners() {
 				}
 			}()
 		}
-		c.timer = time.AfterFunc(c.Timeout, func() {
-			c.Stop()
-			c.Remove()
-		})
+		c.timer = time.AfterFunc(c.Timeout, c.Close)
 	}
 }
 
+// Close stops the timers and removes the notification from the DOM
+func (c *Notify) Close() {
+	c.Stop()
+	c.Remove()
+}
+
 func (c *Notify) Stop() {
 	if c.timer != nil {
 		c.timer.Stop()
@@ -95,3 +93,48 @@ func (c *Notify) Stop() {
 		c.ticker.Stop()
 	}
 }
+
+/******************************************************************************
+* Server-pushed notifications
+******************************************************************************/
+
+// NotifyData is the json payload of a notification pushed by the server,
+// ie. with the spaserver SSEBroadcaster: `webserver.SSE.Publish("notify", data)`
+type NotifyData struct {
+	Message string `json:"message"`           // the message to display, can include html sanitized with the default policy
+	Classes string `json:"classes,omitempty"` // optional classes added to the notification, ie. "is-info toast"
+	Timeout int    `json:"timeout,omitempty"` // optional timeout in seconds, the notification stays until closed if 0
+}
+
+// PopServerNotifications renders a Notify component into _container for every event received on _events,
+// until the channel is closed. Event data is expected to be a json NotifyData, otherwise it's displayed as a plain text message.
+// Messages are sanitized, so the server can forward messages it does not trust.
+//
+// _events is usually provided by an EventSource listening to the server:
+//
+//	es := ick.NewEventSource("/sse", "notify")
+//	ui.PopServerNotifications(webapp.ChildById("notif_container"), es.Events())
+func PopServerNotifications(_container *ick.Element, _events <-chan ick.ServerEvent) {
+	go func() {
+		for evt := range _events {
+			// a plain message is text, a json message can include html which is sanitized
+			var message string
+			var data NotifyData
+			if err := evt.Decode(&data); err != nil {
+				data = NotifyData{}
+				message = html.EscapeString(evt.Data)
+			} else {
+				message = sanitize.HTML(data.Message)
+			}
+
+			notif := &Notify{
+				Message: ick.HTML(message),
+				Timeout: time.Duration(data.Timeout) * time.Second,
+			}
+			if data.Classes != "" {
+				notif.MountClasses = ick.ParseClasses(data.Classes)
+			}
+			_container.RenderComponent(notif, nil)
+		}
+	}()
+}